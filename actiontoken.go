@@ -0,0 +1,169 @@
+package gohelpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultActionTokenTTL is the lifetime of the action tokens issued with a zero ttl.
+const DefaultActionTokenTTL = time.Hour
+
+// ActionTokenOptions config of `NewActionTokens`.
+type ActionTokenOptions struct {
+	Secret []byte      // required, the action tokens are signed with a key derived from it
+	Issuer string      // optional iss claim, checked on consume
+	Store  ReplayCache // remembers the consumed jti, default: a `MemoryReplayCache`, use a shared store on several instances
+	Clock  Clock       // default: SystemClock
+
+	// Fingerprint is optional, it returns the current state a token depends on, e.g. the user password hash for "reset_password"
+	// or the email for "verify_email". A token is rejected once the fingerprint changes, thus a password change invalidates
+	// the pending reset tokens. Only an HMAC of the fingerprint is stored in the token.
+	Fingerprint func(purpose, subject string) (string, error)
+}
+
+/*
+ActionTokens issues and consumes single-use tokens of a purpose, e.g. email verification or password reset links:
+
+	actions := gohelpers.NewActionTokens(gohelpers.ActionTokenOptions{
+		Secret: secret,
+		Fingerprint: func(purpose, userID string) (string, error) {
+			user, err := users.Find(userID)
+			return user.PasswordHash, err
+		},
+	})
+	token, err := actions.IssueActionToken("reset_password", userID, 30*time.Minute)
+	// later, when the link is opened:
+	userID, err := actions.ConsumeActionToken("reset_password", token)
+
+The tokens are signed with a key derived from the secret, thus they are rejected as access tokens by `VerifyJwtToken`,
+and the purpose claim keeps a token of one flow out of the others.
+*/
+type ActionTokens struct {
+	opts     ActionTokenOptions
+	key      []byte
+	store    ReplayCache
+	issuer   *TokenIssuer
+	verifier *Verifier
+}
+
+// NewActionTokens builds an `ActionTokens` from the options, share it: its default store is in memory.
+func NewActionTokens(opts ActionTokenOptions) (*ActionTokens, error) {
+	if len(opts.Secret) == 0 {
+		return nil, &TokenError{Kind: TokenMisconfigured, Err: errors.New("missing secret")}
+	}
+
+	key := deriveKey(opts.Secret, "gohelpers action token")
+	clock := clockOrSystem(opts.Clock)
+	store := opts.Store
+	if store == nil {
+		store = NewMemoryReplayCache(clock)
+	}
+
+	issuer, err := NewTokenIssuer(WithSecret(key), WithIssuer(opts.Issuer), WithClock(clock))
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := NewVerifier(ParseOptions{
+		Secret:         key,
+		Issuer:         opts.Issuer,
+		Clock:          clock,
+		RequireExp:     true,
+		RequiredClaims: []string{"sub", "jti", "purpose"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ActionTokens{
+		opts:     opts,
+		key:      key,
+		store:    store,
+		issuer:   issuer,
+		verifier: verifier,
+	}, nil
+}
+
+// IssueActionToken returns a token of the purpose for the subject (e.g. the user id), valid for ttl (default: DefaultActionTokenTTL).
+func (a *ActionTokens) IssueActionToken(purpose, subject string, ttl time.Duration) (string, error) {
+	if purpose == "" || subject == "" {
+		return "", errors.New("the purpose and subject are required")
+	}
+	if ttl <= 0 {
+		ttl = DefaultActionTokenTTL
+	}
+
+	opts := []TokenOption{WithSubject(subject), WithTTL(ttl), WithClaim("purpose", purpose)}
+	if a.opts.Fingerprint != nil {
+		fingerprint, err := a.fingerprint(purpose, subject)
+		if err != nil {
+			return "", err
+		}
+		opts = append(opts, WithClaim("fgp", fingerprint))
+	}
+
+	return a.issuer.NewToken(opts...)
+}
+
+/*
+ConsumeActionToken verifies a token of the purpose, marks it as used and returns its subject. The error is a `*TokenError`:
+`ErrTokenBadClaims` for a token of another purpose, `ErrTokenRevoked` for a used token or a changed fingerprint.
+A rejected token is not consumed, thus a token of another purpose is still valid in its own flow.
+*/
+func (a *ActionTokens) ConsumeActionToken(purpose, token string) (string, error) {
+	parsed, err := a.verifier.Parse(token)
+	if err != nil {
+		return "", err
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+
+	if got, _ := claims["purpose"].(string); got != purpose {
+		return "", &TokenError{Kind: TokenBadClaims, Err: fmt.Errorf("token purpose %q is not %q", got, purpose)}
+	}
+
+	subject, _ := claims.GetSubject()
+	if a.opts.Fingerprint != nil {
+		fingerprint, err := a.fingerprint(purpose, subject)
+		if err != nil {
+			return "", err
+		}
+		got, _ := claims["fgp"].(string)
+		if !hmac.Equal([]byte(got), []byte(fingerprint)) {
+			return "", &TokenError{Kind: TokenRevoked, Err: errors.New("the token was invalidated, its fingerprint changed")}
+		}
+	}
+
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims.GetExpirationTime()
+	used, err := a.store.Seen("action:"+jti, exp.Add(a.verifier.leeway))
+	if err != nil {
+		return "", &TokenError{Kind: TokenMisconfigured, Err: fmt.Errorf("consumed token store: %w", err)}
+	}
+	if used {
+		return "", &TokenError{Kind: TokenRevoked, Err: errors.New("the token was already used")}
+	}
+
+	return subject, nil
+}
+
+func (a *ActionTokens) fingerprint(purpose, subject string) (string, error) {
+	value, err := a.opts.Fingerprint(purpose, subject)
+	if err != nil {
+		return "", fmt.Errorf("fingerprint: %w", err)
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(purpose + "\x00" + subject + "\x00" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// deriveKey returns a key of the secret for one usage, thus a token signed for one usage can't be verified for another.
+func deriveKey(secret []byte, usage string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(usage))
+	return mac.Sum(nil)
+}
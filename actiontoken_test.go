@@ -0,0 +1,63 @@
+package gohelpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActionTokens(t *testing.T) {
+	secret := []byte("s3cr3t")
+	passwordHashes := map[string]string{"42": "$2a$10$old"}
+	actions, err := NewActionTokens(ActionTokenOptions{
+		Secret: secret,
+		Fingerprint: func(purpose, subject string) (string, error) {
+			return passwordHashes[subject], nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := actions.IssueActionToken("reset_password", "42", 30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := VerifyJwtToken(token, secret); !errors.Is(err, ErrTokenBadSignature) {
+		t.Fatalf("an action token must not verify as an access token, got %v", err)
+	}
+	if _, err := actions.ConsumeActionToken("verify_email", token); !errors.Is(err, ErrTokenBadClaims) {
+		t.Fatalf("other purpose: expected ErrTokenBadClaims, got %v", err)
+	}
+
+	subject, err := actions.ConsumeActionToken("reset_password", token)
+	if err != nil || subject != "42" {
+		t.Fatalf("ConsumeActionToken = %q, %v", subject, err)
+	}
+	if _, err := actions.ConsumeActionToken("reset_password", token); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("second use: expected ErrTokenRevoked, got %v", err)
+	}
+
+	// a password change invalidates the pending tokens
+	token, _ = actions.IssueActionToken("reset_password", "42", 0)
+	passwordHashes["42"] = "$2a$10$new"
+	if _, err := actions.ConsumeActionToken("reset_password", token); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("changed fingerprint: expected ErrTokenRevoked, got %v", err)
+	}
+}
+
+func TestActionTokens_Expiry(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	actions, _ := NewActionTokens(ActionTokenOptions{Secret: []byte("s3cr3t"), Clock: clock})
+
+	token, _ := actions.IssueActionToken("verify_email", "42", 0)
+	clock.Advance(DefaultActionTokenTTL + time.Minute)
+	if _, err := actions.ConsumeActionToken("verify_email", token); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected ErrTokenExpired, got %v", err)
+	}
+
+	if _, err := NewActionTokens(ActionTokenOptions{}); !errors.Is(err, ErrTokenMisconfigured) {
+		t.Fatalf("missing secret: expected ErrTokenMisconfigured, got %v", err)
+	}
+}
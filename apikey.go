@@ -0,0 +1,278 @@
+package gohelpers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// APIKeyPrefix starts every API key, thus the keys are easy to spot, e.g. by secret scanners.
+const APIKeyPrefix = "gh"
+
+// DefaultAPIKeyTouchInterval is how often the last used time of a key is saved when `APIKeyOptions.TouchInterval` is not set.
+const DefaultAPIKeyTouchInterval = time.Minute
+
+// ErrAPIKeyNotFound is returned by an `APIKeyStore` that doesn't have the key.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// APIKey is the stored part of an API key: its metadata and a keyed hash of its secret, never the secret itself.
+type APIKey struct {
+	ID         string // public id, the lookup key of the store
+	Hash       []byte // HMAC-SHA256 of the id and secret
+	Subject    string // the owner, e.g. a user or service id, the sub claim of the requests
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time // zero: never expires
+	LastUsedAt time.Time // zero: never used
+}
+
+// Expired reports whether the key is expired at now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// APIKeyStore persists the API keys, e.g. in a SQL table indexed by id. `MemoryAPIKeyStore` fits the tests and small apps.
+type APIKeyStore interface {
+	SaveAPIKey(key *APIKey) error
+	// GetAPIKey returns `ErrAPIKeyNotFound` when there is no key of the id, e.g. it was revoked.
+	GetAPIKey(id string) (*APIKey, error)
+	// TouchAPIKey saves the last used time of the key.
+	TouchAPIKey(id string, usedAt time.Time) error
+}
+
+// APIKeyOptions config of `NewAPIKeys`.
+type APIKeyOptions struct {
+	Secret        []byte        // required, the HMAC key of the stored hashes, keep it out of the database
+	Store         APIKeyStore   // required
+	Env           string        // environment of the keys, e.g. "live" or "test", the keys of other environments are rejected. Default: "live"
+	TouchInterval time.Duration // the last used time is saved at most once per interval, default: DefaultAPIKeyTouchInterval
+	Clock         Clock         // default: SystemClock
+	Header        string        // header of the keys in requests, default: "X-API-Key", `Authorization: Bearer gh_...` is accepted too
+}
+
+/*
+APIKeys generates and verifies API keys for machine clients, formatted as `gh_<env>_<id>_<secret>`:
+
+	apiKeys, err := gohelpers.NewAPIKeys(gohelpers.APIKeyOptions{Secret: pepper, Store: store})
+	plain, key, err := apiKeys.Generate("service-42", []string{"orders:read"}, 0)
+	// show plain once to the client, then:
+	key, err = apiKeys.Verify(plain)
+
+The id finds the key in the store, and only an HMAC of the secret is stored: it's fast unlike bcrypt, which is fine for
+random secrets of 256 bits, and a leaked table is useless without the HMAC secret. See `AuthOptions.APIKeys` to accept
+the keys in `Authenticate`.
+*/
+type APIKeys struct {
+	opts     APIKeyOptions
+	env      string
+	interval time.Duration
+	clock    Clock
+	header   string
+}
+
+// NewAPIKeys builds an `APIKeys` from the options.
+func NewAPIKeys(opts APIKeyOptions) (*APIKeys, error) {
+	if len(opts.Secret) == 0 || opts.Store == nil {
+		return nil, &TokenError{Kind: TokenMisconfigured, Err: errors.New("the api keys secret and store are required")}
+	}
+
+	env := opts.Env
+	if env == "" {
+		env = "live"
+	}
+	if strings.Contains(env, "_") {
+		return nil, &TokenError{Kind: TokenMisconfigured, Err: fmt.Errorf("the api keys env %q must not contain an underscore", env)}
+	}
+	interval := opts.TouchInterval
+	if interval == 0 {
+		interval = DefaultAPIKeyTouchInterval
+	}
+	header := opts.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+
+	return &APIKeys{opts: opts, env: env, interval: interval, clock: clockOrSystem(opts.Clock), header: header}, nil
+}
+
+// Generate creates a key of the subject and scopes, valid for ttl (zero: never expires), and saves it. The plain key is returned once, it can't be recovered.
+func (a *APIKeys) Generate(subject string, scopes []string, ttl time.Duration) (string, *APIKey, error) {
+	id, err := randomAPIKeyPart(10)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomAPIKeyPart(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := a.clock.Now()
+	key := &APIKey{
+		ID:        id,
+		Hash:      a.hash(id, secret),
+		Subject:   subject,
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+	if err := a.opts.Store.SaveAPIKey(key); err != nil {
+		return "", nil, err
+	}
+
+	return strings.Join([]string{APIKeyPrefix, a.env, id, secret}, "_"), key, nil
+}
+
+/*
+Verify returns the stored key of a plain API key. The secret is compared in constant time, and an unknown id costs the same
+HMAC as a known one. The error is a `*TokenError`: `ErrTokenMalformed`, `ErrTokenInvalid` or `ErrTokenExpired`.
+*/
+func (a *APIKeys) Verify(plain string) (*APIKey, error) {
+	parts := strings.Split(strings.TrimSpace(plain), "_")
+	if len(parts) != 4 || parts[0] != APIKeyPrefix || parts[2] == "" || parts[3] == "" {
+		return nil, &TokenError{Kind: TokenMalformed, Source: SourceAPIKey, Err: errors.New("not an api key")}
+	}
+	env, id, secret := parts[1], parts[2], parts[3]
+	// hashed first, a key of another env takes as long to reject as a wrong secret
+	hash := a.hash(id, secret)
+	if env != a.env {
+		return nil, &TokenError{Kind: TokenInvalid, Source: SourceAPIKey, Err: fmt.Errorf("api key of the %q env", env)}
+	}
+
+	key, err := a.opts.Store.GetAPIKey(id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, &TokenError{Kind: TokenInvalid, Source: SourceAPIKey, Err: err}
+	}
+	if err != nil {
+		return nil, &TokenError{Kind: TokenMisconfigured, Source: SourceAPIKey, Err: fmt.Errorf("api key store: %w", err)}
+	}
+	if !hmac.Equal(hash, key.Hash) {
+		return nil, &TokenError{Kind: TokenInvalid, Source: SourceAPIKey, Err: errors.New("api key secret mismatch")}
+	}
+
+	now := a.clock.Now()
+	if key.Expired(now) {
+		return nil, &TokenError{Kind: TokenExpired, Source: SourceAPIKey, Err: errors.New("api key expired")}
+	}
+	if now.Sub(key.LastUsedAt) >= a.interval {
+		if err := a.opts.Store.TouchAPIKey(id, now); err == nil {
+			key.LastUsedAt = now
+		}
+	}
+
+	return key, nil
+}
+
+// extract returns the API key of the request, empty when there's none.
+func (a *APIKeys) extract(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(a.header)); key != "" {
+		return key
+	}
+	token, _, _ := AuthHeaderExtractor().ExtractToken(r)
+	if strings.HasPrefix(token, APIKeyPrefix+"_") {
+		return token
+	}
+	return ""
+}
+
+// bearerJWT reports whether the Authorization header carries a token that is not an API key, thus a JWT.
+func (a *APIKeys) bearerJWT(r *http.Request) bool {
+	token, _, _ := AuthHeaderExtractor().ExtractToken(r)
+	return token != "" && !strings.HasPrefix(token, APIKeyPrefix+"_")
+}
+
+func (a *APIKeys) hash(id, secret string) []byte {
+	mac := hmac.New(sha256.New, a.opts.Secret)
+	mac.Write([]byte(id + "." + secret))
+	return mac.Sum(nil)
+}
+
+func randomAPIKeyPart(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(apiKeyEncoding.EncodeToString(b)), nil
+}
+
+// APIKeyClaims returns the claims `Authenticate` stores for a request authenticated with the key: sub, scope (space-delimited) and api_key_id.
+func APIKeyClaims(key *APIKey) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":        key.Subject,
+		"scope":      strings.Join(key.Scopes, " "),
+		"api_key_id": key.ID,
+	}
+}
+
+// ContextWithAPIKey returns a copy of ctx that holds the key and its `APIKeyClaims`.
+func ContextWithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	ctx = context.WithValue(ctx, apiKeyContextKey, key)
+	ctx = context.WithValue(ctx, claimsContextKey, APIKeyClaims(key))
+	return context.WithValue(ctx, sourceContextKey, SourceAPIKey)
+}
+
+// APIKeyFromContext returns the API key stored by `Authenticate`, when the request was authenticated with a key rather than a token.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(*APIKey)
+	return key, ok && key != nil
+}
+
+// MemoryAPIKeyStore is an in-memory `APIKeyStore`, safe for concurrent use.
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]APIKey
+}
+
+// NewMemoryAPIKeyStore returns an empty store.
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: map[string]APIKey{}}
+}
+
+func (s *MemoryAPIKeyStore) SaveAPIKey(key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.ID] = *key
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) GetAPIKey(id string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+func (s *MemoryAPIKeyStore) TouchAPIKey(id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = usedAt
+	s.keys[id] = key
+	return nil
+}
+
+// DeleteAPIKey revokes the key.
+func (s *MemoryAPIKeyStore) DeleteAPIKey(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, id)
+}
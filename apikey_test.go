@@ -0,0 +1,129 @@
+package gohelpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys_GenerateVerify(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryAPIKeyStore()
+	apiKeys, err := NewAPIKeys(APIKeyOptions{Secret: []byte("pepper"), Store: store, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, key, err := apiKeys.Generate("service-42", []string{"orders:read"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, "gh_live_"+key.ID+"_") || strings.Contains(string(key.Hash), plain) {
+		t.Fatalf("unexpected key %q, %+v", plain, key)
+	}
+
+	verified, err := apiKeys.Verify(plain)
+	if err != nil || verified.Subject != "service-42" || !verified.LastUsedAt.Equal(clock.Now()) {
+		t.Fatalf("Verify = %+v, %v", verified, err)
+	}
+	stored, _ := store.GetAPIKey(key.ID)
+	if !stored.LastUsedAt.Equal(clock.Now()) {
+		t.Fatalf("the last used time was not saved: %v", stored.LastUsedAt)
+	}
+
+	wrongSecret := plain[:len(plain)-1] + "a"
+	if strings.HasSuffix(plain, "a") {
+		wrongSecret = plain[:len(plain)-1] + "b"
+	}
+	invalid := []struct {
+		name string
+		key  string
+		want error
+	}{
+		{"not a key", "abc", ErrTokenMalformed},
+		{"other env", strings.Replace(plain, "_live_", "_test_", 1), ErrTokenInvalid},
+		{"wrong secret", wrongSecret, ErrTokenInvalid},
+		{"unknown id", "gh_live_aaaaaaaaaaaaaaaa_" + plain[strings.LastIndex(plain, "_")+1:], ErrTokenInvalid},
+	}
+	for _, c := range invalid {
+		if _, err := apiKeys.Verify(c.key); !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+
+	clock.Advance(2 * time.Hour)
+	if _, err := apiKeys.Verify(plain); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected ErrTokenExpired, got %v", err)
+	}
+
+	store.DeleteAPIKey(key.ID)
+	if _, err := apiKeys.Verify(plain); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("revoked key: expected ErrTokenInvalid, got %v", err)
+	}
+}
+
+func TestAuthenticate_APIKeyOrJWT(t *testing.T) {
+	secret := []byte("s3cr3t")
+	apiKeys, _ := NewAPIKeys(APIKeyOptions{Secret: []byte("pepper"), Store: NewMemoryAPIKeyStore()})
+	plain, _, _ := apiKeys.Generate("service-42", []string{"orders:read"}, 0)
+	token, _ := NewToken(WithSecret(secret), WithSubject("user-1"), WithClaim("scope", "orders:read"))
+
+	var subject string
+	var source TokenSource
+	handler := Authenticate(AuthOptions{ParseOptions: ParseOptions{Secret: secret}, APIKeys: apiKeys})(
+		RequireScopes("orders:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ := ClaimsFromContext[map[string]interface{}](r.Context())
+			subject, _ = claims["sub"].(string)
+			source, _ = TokenSourceFromContext(r.Context())
+		})),
+	)
+
+	cases := []struct {
+		name        string
+		header      string
+		value       string
+		wantStatus  int
+		wantSubject string
+		wantSource  TokenSource
+	}{
+		{"api key header", "X-API-Key", plain, http.StatusOK, "service-42", SourceAPIKey},
+		{"api key bearer", "Authorization", "Bearer " + plain, http.StatusOK, "service-42", SourceAPIKey},
+		{"jwt", "Authorization", "Bearer " + token, http.StatusOK, "user-1", SourceHeader},
+		{"bad api key", "X-API-Key", plain + "x", http.StatusUnauthorized, "", ""},
+	}
+	for _, c := range cases {
+		subject, source = "", ""
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set(c.header, c.value)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != c.wantStatus || subject != c.wantSubject || source != c.wantSource {
+			t.Errorf("%s: got %d %q %q, want %d %q %q", c.name, rec.Code, subject, source, c.wantStatus, c.wantSubject, c.wantSource)
+		}
+	}
+
+	// an invalid X-API-Key falls back to the JWT bearer
+	subject, source = "", ""
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("X-API-Key", plain+"x")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || subject != "user-1" || source != SourceHeader {
+		t.Errorf("bad api key with jwt: got %d %q %q, want 200 user-1 header", rec.Code, subject, source)
+	}
+
+	// it's still rejected when the extractor doesn't read the header, even with Optional
+	optional := Authenticate(AuthOptions{ParseOptions: ParseOptions{Secret: secret, Extractor: CookieExtractor("access_token")}, Optional: true, APIKeys: apiKeys})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	rec = httptest.NewRecorder()
+	optional.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad api key without jwt extractor: got %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,152 @@
+package gohelpers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Authorizer config for the scope and role middlewares. The zero value is ready to use.
+type Authorizer struct {
+	ScopeClaim string // default: "scope", a space-delimited string (RFC 8693)
+	RolesClaim string // default: "roles", an array of strings
+
+	// ErrorHandler writes the response when the check fails. Default: `WriteBearerError`, 403 with an `insufficient_scope` error.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// InsufficientScopeError is returned when the token doesn't carry the required scopes or roles.
+type InsufficientScopeError struct {
+	Claim    string   // the claim that was checked
+	Required []string // what the route requires
+	Missing  []string // what the token is missing
+	Any      bool     // true when one of Required is enough
+}
+
+func (e *InsufficientScopeError) Error() string {
+	if e.Any {
+		return fmt.Sprintf("insufficient scope: %q requires one of [%s]", e.Claim, strings.Join(e.Required, " "))
+	}
+	return fmt.Sprintf("insufficient scope: %q is missing [%s]", e.Claim, strings.Join(e.Missing, " "))
+}
+
+// RequireScopes rejects requests whose token doesn't have all the scopes, using the default `Authorizer`.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return Authorizer{}.RequireScopes(scopes...)
+}
+
+// RequireAnyRole rejects requests whose token doesn't have at least one of the roles, using the default `Authorizer`.
+func RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
+	return Authorizer{}.RequireAnyRole(roles...)
+}
+
+// RequireAllRoles rejects requests whose token doesn't have all the roles, using the default `Authorizer`.
+func RequireAllRoles(roles ...string) func(http.Handler) http.Handler {
+	return Authorizer{}.RequireAllRoles(roles...)
+}
+
+/*
+RequireScopes returns a middleware that checks the scope claim of the token stored by `Authenticate`.
+It must be mounted after `Authenticate`, a request without a verified token is rejected with 401.
+The middlewares panic without any scope or role, a route guarded by nothing is a setup mistake.
+*/
+func (a Authorizer) RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return a.require(a.scopeClaim(), scopes, false, true)
+}
+
+// RequireAnyRole returns a middleware that passes when the token has at least one of the roles.
+func (a Authorizer) RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
+	return a.require(a.rolesClaim(), roles, true, false)
+}
+
+// RequireAllRoles returns a middleware that passes when the token has all the roles.
+func (a Authorizer) RequireAllRoles(roles ...string) func(http.Handler) http.Handler {
+	return a.require(a.rolesClaim(), roles, false, false)
+}
+
+func (a Authorizer) require(claim string, required []string, anyOf, spaceDelimited bool) func(http.Handler) http.Handler {
+	if len(required) == 0 {
+		panic(fmt.Sprintf("gohelpers: no required values for the %q claim", claim))
+	}
+	errorHandler := a.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = WriteBearerError
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext[jwt.MapClaims](r.Context())
+			if !ok {
+				errorHandler(w, r, ErrTokenMissing)
+				return
+			}
+
+			if err := checkClaimValues(claims, claim, required, anyOf, spaceDelimited); err != nil {
+				errorHandler(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a Authorizer) scopeClaim() string {
+	if a.ScopeClaim == "" {
+		return "scope"
+	}
+	return a.ScopeClaim
+}
+
+func (a Authorizer) rolesClaim() string {
+	if a.RolesClaim == "" {
+		return "roles"
+	}
+	return a.RolesClaim
+}
+
+func checkClaimValues(claims jwt.MapClaims, claim string, required []string, anyOf, spaceDelimited bool) error {
+	granted := claimStrings(claims[claim], spaceDelimited)
+
+	var missing []string
+	for _, want := range required {
+		if InSlice(want, granted) {
+			if anyOf {
+				return nil
+			}
+			continue
+		}
+		missing = append(missing, want)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return &InsufficientScopeError{Claim: claim, Required: required, Missing: missing, Any: anyOf}
+}
+
+// claimStrings reads a claim as a list: an array of strings, or a space-delimited string when the claim format is one, e.g. scope.
+func claimStrings(v interface{}, spaceDelimited bool) []string {
+	switch t := v.(type) {
+	case string:
+		if !spaceDelimited {
+			return nil
+		}
+		return strings.Fields(t)
+	case []string:
+		return t
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package gohelpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func serveWithClaims(t *testing.T, mw func(http.Handler) http.Handler, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	t.Helper()
+	secret := []byte("s3cr3t")
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	tok, _ := GenerateJwtToken(secret, claims)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := Authenticate(AuthOptions{ParseOptions: ParseOptions{Secret: secret}})(mw(ok))
+
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireScopes(t *testing.T) {
+	rec := serveWithClaims(t, RequireScopes("read:users", "write:users"), jwt.MapClaims{"scope": "read:users write:users admin"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("all scopes granted: got %d", rec.Code)
+	}
+
+	rec = serveWithClaims(t, RequireScopes("read:users", "write:users"), jwt.MapClaims{"scope": "read:users"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("missing scope: got %d, want 403", rec.Code)
+	}
+	if !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`) {
+		t.Fatalf("missing insufficient_scope header: %q", rec.Header().Get("WWW-Authenticate"))
+	}
+	if !strings.Contains(rec.Body.String(), `"insufficient_scope"`) {
+		t.Fatalf("missing insufficient_scope body: %s", rec.Body.String())
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	claims := func() jwt.MapClaims { return jwt.MapClaims{"roles": []string{"editor", "viewer"}} }
+
+	if rec := serveWithClaims(t, RequireAnyRole("admin", "editor"), claims()); rec.Code != http.StatusOK {
+		t.Fatalf("RequireAnyRole: got %d, want 200", rec.Code)
+	}
+	if rec := serveWithClaims(t, RequireAllRoles("admin", "editor"), claims()); rec.Code != http.StatusForbidden {
+		t.Fatalf("RequireAllRoles: got %d, want 403", rec.Code)
+	}
+
+	custom := Authorizer{RolesClaim: "groups"}
+	if rec := serveWithClaims(t, custom.RequireAllRoles("ops"), jwt.MapClaims{"groups": []string{"ops"}}); rec.Code != http.StatusOK {
+		t.Fatalf("custom roles claim: got %d, want 200", rec.Code)
+	}
+
+	// roles is an array, a string is not split
+	if rec := serveWithClaims(t, RequireAnyRole("admin"), jwt.MapClaims{"roles": "viewer admin"}); rec.Code != http.StatusForbidden {
+		t.Fatalf("string roles claim: got %d, want 403", rec.Code)
+	}
+}
+
+func TestRequireRoles_Empty(t *testing.T) {
+	for name, mw := range map[string]func(...string) func(http.Handler) http.Handler{
+		"RequireAnyRole":  RequireAnyRole,
+		"RequireAllRoles": RequireAllRoles,
+		"RequireScopes":   RequireScopes,
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic without values", name)
+				}
+			}()
+			mw()
+		}()
+	}
+}
+
+func TestRequireScopes_WithoutAuthenticate(t *testing.T) {
+	handler := RequireScopes("read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://x.local/api", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("got %d, want 401", rec.Code)
+	}
+}
+
+func TestInsufficientScopeError(t *testing.T) {
+	err := checkClaimValues(jwt.MapClaims{"scope": "a"}, "scope", []string{"a", "b"}, false, true)
+
+	var scopeErr *InsufficientScopeError
+	if !errors.As(err, &scopeErr) || len(scopeErr.Missing) != 1 || scopeErr.Missing[0] != "b" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
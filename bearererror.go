@@ -0,0 +1,176 @@
+package gohelpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error codes of RFC 6750, section 3.1.
+const (
+	BearerInvalidRequest    = "invalid_request"
+	BearerInvalidToken      = "invalid_token"
+	BearerInsufficientScope = "insufficient_scope"
+	BearerInvalidDPoPProof  = "invalid_dpop_proof" // RFC 9449, section 7.1
+)
+
+// BearerError is the HTTP response an auth failure maps to, see `NewBearerError`.
+type BearerError struct {
+	Status      int    // HTTP status code
+	Code        string // RFC 6750 error code, empty when the request has no token at all
+	Description string // human readable error_description
+	Scope       string // required scopes, set with insufficient_scope
+	Scheme      string // scheme of the WWW-Authenticate challenge, default: "Bearer"
+}
+
+/*
+NewBearerError maps an error of `ParseFromRequest`, `Authenticate` or the authorization middlewares to its RFC 6750 response:
+  - no token in the request: 401 without an error code
+  - expired, not valid yet, bad signature, malformed...: 401 "invalid_token"
+  - missing scopes or roles: 403 "insufficient_scope"
+  - different tokens in the same request: 400 "invalid_request"
+  - missing or invalid DPoP proof: 401 "invalid_dpop_proof", with a DPoP challenge
+  - missing secret (server misconfiguration): 500
+*/
+func NewBearerError(err error) BearerError {
+	var scopeErr *InsufficientScopeError
+	if errors.As(err, &scopeErr) {
+		return BearerError{
+			Status:      http.StatusForbidden,
+			Code:        BearerInsufficientScope,
+			Description: "the access token does not have the required scope",
+			Scope:       strings.Join(scopeErr.Required, " "),
+		}
+	}
+
+	var tokenErr *TokenError
+	if !errors.As(err, &tokenErr) {
+		return invalidToken("the access token is invalid")
+	}
+
+	switch tokenErr.Kind {
+	case TokenMissing:
+		return BearerError{Status: http.StatusUnauthorized}
+	case TokenMisconfigured:
+		return BearerError{Status: http.StatusInternalServerError}
+	case TokenConflict:
+		return BearerError{Status: http.StatusBadRequest, Code: BearerInvalidRequest, Description: "the request carries more than one token"}
+	case TokenBadProof:
+		return BearerError{Status: http.StatusUnauthorized, Code: BearerInvalidDPoPProof, Description: "the DPoP proof is missing or invalid", Scheme: "DPoP"}
+	case TokenExpired:
+		return invalidToken("the access token expired")
+	case TokenNotYetValid:
+		return invalidToken("the access token is not valid yet")
+	case TokenBadSignature:
+		return invalidToken("the access token signature is invalid")
+	case TokenBadMethod:
+		return invalidToken("the access token signing method is not allowed")
+	case TokenMalformed:
+		return invalidToken("the access token is malformed")
+	case TokenBadAudience:
+		return invalidToken("the access token audience is invalid")
+	case TokenBadIssuer:
+		return invalidToken("the access token issuer is invalid")
+	case TokenRevoked:
+		return invalidToken("the access token was revoked")
+	case TokenMissingClaim:
+		return invalidToken("the access token is missing a required claim")
+	case TokenTooOld:
+		return invalidToken("the access token is too old")
+	case TokenUnknownKey:
+		return invalidToken("the access token key is unknown")
+	default:
+		return invalidToken("the access token is invalid")
+	}
+}
+
+// BearerErrorWriter writes auth failures as RFC 6750 responses, use its `WriteError` as an `ErrorHandler`.
+type BearerErrorWriter struct {
+	Realm   string // optional realm of the WWW-Authenticate header
+	Problem bool   // write an RFC 7807 application/problem+json body instead of the OAuth error JSON
+}
+
+// WriteBearerError writes err with the default `BearerErrorWriter`.
+func WriteBearerError(w http.ResponseWriter, r *http.Request, err error) {
+	BearerErrorWriter{}.WriteError(w, r, err)
+}
+
+/*
+WriteError sets the `WWW-Authenticate: Bearer realm="...", error="...", error_description="..."` header and writes the status
+and body of `NewBearerError(err)`. Clients could use the error code to tell "refresh your token" (401 invalid_token)
+from "you are not allowed" (403 insufficient_scope).
+*/
+func (bw BearerErrorWriter) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	be := NewBearerError(err)
+
+	if be.Status == http.StatusUnauthorized || be.Status == http.StatusForbidden || be.Status == http.StatusBadRequest {
+		w.Header().Set("WWW-Authenticate", bw.challenge(be))
+	}
+
+	body := map[string]interface{}{}
+	if bw.Problem {
+		w.Header().Set("Content-Type", "application/problem+json")
+		body["type"] = "about:blank"
+		body["title"] = http.StatusText(be.Status)
+		body["status"] = be.Status
+		if be.Description != "" {
+			body["detail"] = be.Description
+		}
+		if be.Code != "" {
+			body["error"] = be.Code
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		body["error"] = be.Code
+		if be.Code == "" {
+			body["error"] = strings.ToLower(strings.ReplaceAll(http.StatusText(be.Status), " ", "_"))
+		}
+		if be.Description != "" {
+			body["error_description"] = be.Description
+		}
+	}
+	if be.Scope != "" {
+		body["scope"] = be.Scope
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(be.Status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (bw BearerErrorWriter) challenge(be BearerError) string {
+	var params []string
+
+	if bw.Realm != "" {
+		params = append(params, fmt.Sprintf("realm=%s", quoteAuthParam(bw.Realm)))
+	}
+	if be.Code != "" {
+		params = append(params, fmt.Sprintf("error=%s", quoteAuthParam(be.Code)))
+	}
+	if be.Description != "" {
+		params = append(params, fmt.Sprintf("error_description=%s", quoteAuthParam(be.Description)))
+	}
+	if be.Scope != "" {
+		params = append(params, fmt.Sprintf("scope=%s", quoteAuthParam(be.Scope)))
+	}
+
+	scheme := be.Scheme
+	if scheme == "" {
+		scheme = "Bearer"
+	}
+	if len(params) == 0 {
+		return scheme
+	}
+
+	return scheme + " " + strings.Join(params, ", ")
+}
+
+func invalidToken(description string) BearerError {
+	return BearerError{Status: http.StatusUnauthorized, Code: BearerInvalidToken, Description: description}
+}
+
+func quoteAuthParam(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+}
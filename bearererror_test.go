@@ -0,0 +1,89 @@
+package gohelpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewBearerError(t *testing.T) {
+	secret := []byte("right")
+	expired := createCustomClaim(time.Now().Add(-1 * time.Minute))
+	tokExpired, _ := GenerateJwtToken(secret, &expired)
+	valid := createCustomClaim(time.Now().Add(time.Minute))
+	tokValid, _ := GenerateJwtToken(secret, &valid)
+
+	cases := []struct {
+		name       string
+		header     string
+		opts       ParseOptions
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing token", "", ParseOptions{Secret: secret}, http.StatusUnauthorized, ""},
+		{"expired", "Bearer " + tokExpired, ParseOptions{Secret: secret}, http.StatusUnauthorized, BearerInvalidToken},
+		{"bad signature", "Bearer " + tokValid, ParseOptions{Secret: []byte("wrong")}, http.StatusUnauthorized, BearerInvalidToken},
+		{"malformed", "Bearer abc.def", ParseOptions{Secret: secret}, http.StatusUnauthorized, BearerInvalidToken},
+		{"missing secret", "Bearer " + tokValid, ParseOptions{}, http.StatusInternalServerError, ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		_, err := ParseFromRequest(req, c.opts)
+		be := NewBearerError(err)
+
+		if be.Status != c.wantStatus || be.Code != c.wantCode {
+			t.Errorf("%s: got %d %q, want %d %q", c.name, be.Status, be.Code, c.wantStatus, c.wantCode)
+		}
+	}
+
+	be := NewBearerError(&InsufficientScopeError{Claim: "scope", Required: []string{"a", "b"}})
+	if be.Status != http.StatusForbidden || be.Code != BearerInsufficientScope || be.Scope != "a b" {
+		t.Errorf("insufficient scope: got %+v", be)
+	}
+}
+
+func TestBearerErrorWriter(t *testing.T) {
+	secret := []byte("right")
+	expired := createCustomClaim(time.Now().Add(-1 * time.Minute))
+	tok, _ := GenerateJwtToken(secret, &expired)
+
+	handler := Authenticate(AuthOptions{
+		ParseOptions: ParseOptions{Secret: secret},
+		ErrorHandler: BearerErrorWriter{Realm: "api", Problem: true}.WriteError,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	want := `Bearer realm="api", error="invalid_token", error_description="the access token expired"`
+	if got := rec.Header().Get("WWW-Authenticate"); got != want {
+		t.Fatalf("WWW-Authenticate = %q, want %q", got, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	var problem map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	if problem["status"] != float64(http.StatusUnauthorized) || problem["error"] != BearerInvalidToken {
+		t.Fatalf("unexpected problem body: %v", problem)
+	}
+
+	// no token: a bare challenge without error attributes
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://x.local/api", nil))
+	if got := rec.Header().Get("WWW-Authenticate"); strings.Contains(got, "error=") {
+		t.Fatalf("missing token should not carry an error code: %q", got)
+	}
+}
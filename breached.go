@@ -0,0 +1,280 @@
+package gohelpers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBreachedPasswordsURL is the range API of Have I Been Pwned, the default URL of `HTTPBreachedSource`.
+const DefaultBreachedPasswordsURL = "https://api.pwnedpasswords.com/range/"
+
+/*
+BreachedPasswordSource looks up the breached passwords by range, the k-anonymity format popularized by Have I Been Pwned:
+the password SHA-1 (40 uppercase hex characters) is split in a prefix of 5 characters and a suffix of 35, only the prefix
+is sent to the source, which returns all the suffixes of the prefix with their breach counts.
+*/
+type BreachedPasswordSource interface {
+	// Range returns the breach counts of the hashes of the prefix, keyed by their uppercase suffix. The ctx cancels the lookup.
+	Range(ctx context.Context, prefix string) (map[string]int, error)
+}
+
+/*
+BreachedPasswords checks the passwords against a `BreachedPasswordSource`, e.g. at signup next to `HashPassword`:
+
+	breached := &gohelpers.BreachedPasswords{Source: &gohelpers.HTTPBreachedSource{}, Threshold: 3}
+	policy := gohelpers.PasswordPolicy{Breached: breached}
+	if err := policy.ValidateContext(r.Context(), password, email); err != nil {
+		return err
+	}
+	hashed, err := gohelpers.HashPassword(password)
+
+The password itself never leaves the process, only the first 5 characters of its SHA-1.
+*/
+type BreachedPasswords struct {
+	Source    BreachedPasswordSource // required
+	Threshold int                    // min breach count of a rejected password, default: 1
+}
+
+// Count returns how many times the password appears in the breaches of the source, 0 when it doesn't.
+func (b *BreachedPasswords) Count(ctx context.Context, password string) (int, error) {
+	if b.Source == nil {
+		return 0, errors.New("missing breached passwords source")
+	}
+
+	hash := passwordSHA1(password)
+	counts, err := b.Source.Range(ctx, hash[:5])
+	if err != nil {
+		return 0, fmt.Errorf("breached passwords range %s: %w", hash[:5], err)
+	}
+	return counts[hash[5:]], nil
+}
+
+// Breached reports whether the password appears at least `Threshold` times in the breaches.
+func (b *BreachedPasswords) Breached(ctx context.Context, password string) (bool, error) {
+	count, err := b.Count(ctx, password)
+	if err != nil {
+		return false, err
+	}
+	return count > 0 && count >= max(b.Threshold, 1), nil
+}
+
+func passwordSHA1(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// MemoryBreachedSource is an in-memory `BreachedPasswordSource`, safe for concurrent use, e.g. for the tests or a small own list.
+// The zero value is an empty source.
+type MemoryBreachedSource struct {
+	mu     sync.RWMutex
+	counts map[string]int // by full uppercase SHA-1
+}
+
+// NewMemoryBreachedSource returns an empty source.
+func NewMemoryBreachedSource() *MemoryBreachedSource {
+	return &MemoryBreachedSource{counts: map[string]int{}}
+}
+
+// Add adds the password with its breach count.
+func (s *MemoryBreachedSource) Add(password string, count int) {
+	s.AddHash(passwordSHA1(password), count)
+}
+
+// AddHash adds the SHA-1 hex of a password with its breach count.
+func (s *MemoryBreachedSource) AddHash(hash string, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = map[string]int{}
+	}
+	s.counts[strings.ToUpper(hash)] = count
+}
+
+func (s *MemoryBreachedSource) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prefix = strings.ToUpper(prefix)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := map[string]int{}
+	for hash, count := range s.counts {
+		if suffix, ok := strings.CutPrefix(hash, prefix); ok {
+			counts[suffix] = count
+		}
+	}
+	return counts, nil
+}
+
+/*
+FileBreachedSource is a `BreachedPasswordSource` of a local file of `SHA1:COUNT` lines sorted by hash, the format of the
+Have I Been Pwned downloader. The file is binary searched, it's never loaded in memory, thus it could be tens of GB.
+*/
+type FileBreachedSource struct {
+	file *os.File
+	size int64
+}
+
+// NewFileBreachedSource opens the file, close it with `Close`.
+func NewFileBreachedSource(path string) (*FileBreachedSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &FileBreachedSource{file: file, size: info.Size()}, nil
+}
+
+// Close closes the file.
+func (s *FileBreachedSource) Close() error {
+	return s.file.Close()
+}
+
+func (s *FileBreachedSource) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prefix = strings.ToUpper(prefix)
+
+	// the smallest offset whose line is not before the prefix
+	lo, hi := int64(0), s.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := s.lineAt(mid)
+		if err != nil {
+			return nil, err
+		}
+		if start >= s.size || strings.ToUpper(line[:min(len(line), len(prefix))]) >= prefix {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	start, _, err := s.lineAt(lo)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	scanner := bufio.NewScanner(io.NewSectionReader(s.file, start, s.size-start))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(strings.ToUpper(line), prefix) {
+			break
+		}
+		suffix, count, err := parseBreachedLine(line[len(prefix):])
+		if err != nil {
+			return nil, err
+		}
+		counts[suffix] = count
+	}
+	return counts, scanner.Err()
+}
+
+// lineAt returns the start and content of the first line that starts at or after offset, start is the file size when there's none.
+func (s *FileBreachedSource) lineAt(offset int64) (int64, string, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(s.file, start, s.size-start))
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		start += int64(len(skipped))
+		if err == io.EOF {
+			return s.size, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	if line == "" {
+		return s.size, "", nil
+	}
+	return start, strings.TrimSpace(line), nil
+}
+
+/*
+HTTPBreachedSource is a `BreachedPasswordSource` of a range API, GET <URL><prefix> returns `SUFFIX:COUNT` lines.
+The zero value queries Have I Been Pwned, with padding to hide the size of the responses.
+*/
+type HTTPBreachedSource struct {
+	URL    string       // default: DefaultBreachedPasswordsURL
+	Client *http.Client // default: a client with a 10s timeout
+}
+
+var defaultBreachedClient = &http.Client{Timeout: 10 * time.Second}
+
+func (s *HTTPBreachedSource) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	url := s.URL
+	if url == "" {
+		url = DefaultBreachedPasswordsURL
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultBreachedClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+strings.ToUpper(prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "gohelpers")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	counts := map[string]int{}
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		suffix, count, err := parseBreachedLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 { // the padding lines have a zero count
+			counts[suffix] = count
+		}
+	}
+	return counts, scanner.Err()
+}
+
+func parseBreachedLine(line string) (string, int, error) {
+	suffix, rawCount, ok := strings.Cut(line, ":")
+	count, err := strconv.Atoi(strings.TrimSpace(rawCount))
+	if !ok || err != nil || count < 0 {
+		return "", 0, fmt.Errorf("malformed breached passwords line %q", line)
+	}
+	return strings.ToUpper(suffix), count, nil
+}
@@ -0,0 +1,194 @@
+package gohelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPasswordSHA1(t *testing.T) {
+	// the Have I Been Pwned example
+	if got := passwordSHA1("password"); got != "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+		t.Fatalf("unexpected hash %s", got)
+	}
+}
+
+func TestBreachedPasswords_Memory(t *testing.T) {
+	source := NewMemoryBreachedSource()
+	source.Add("password", 10)
+	source.Add("rare one", 1)
+
+	breached := &BreachedPasswords{Source: source, Threshold: 2}
+	cases := []struct {
+		password string
+		count    int
+		breached bool
+	}{
+		{"password", 10, true},
+		{"rare one", 1, false},
+		{"correct horse battery", 0, false},
+	}
+	for _, c := range cases {
+		count, err := breached.Count(context.Background(), c.password)
+		if err != nil || count != c.count {
+			t.Errorf("%q: Count = %d, %v", c.password, count, err)
+		}
+		if got, _ := breached.Breached(context.Background(), c.password); got != c.breached {
+			t.Errorf("%q: Breached = %v", c.password, got)
+		}
+	}
+
+	if _, err := (&BreachedPasswords{}).Count(context.Background(), "password"); err == nil {
+		t.Error("expected an error without a source")
+	}
+
+	// the zero value is usable
+	var zero MemoryBreachedSource
+	if count, err := (&BreachedPasswords{Source: &zero}).Count(context.Background(), "password"); err != nil || count != 0 {
+		t.Errorf("empty zero value: Count = %d, %v", count, err)
+	}
+	zero.Add("password", 3)
+	if count, _ := (&BreachedPasswords{Source: &zero}).Count(context.Background(), "password"); count != 3 {
+		t.Errorf("zero value: Count = %d, want 3", count)
+	}
+}
+
+func TestFileBreachedSource(t *testing.T) {
+	hashes := []string{passwordSHA1("password"), passwordSHA1("letmein")}
+	for i := 0; i < 500; i++ {
+		hashes = append(hashes, passwordSHA1(fmt.Sprintf("filler %d", i)))
+	}
+	sort.Strings(hashes)
+
+	var lines []string
+	for i, hash := range hashes {
+		lines = append(lines, fmt.Sprintf("%s:%d", hash, i+1))
+	}
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewFileBreachedSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	breached := &BreachedPasswords{Source: source}
+	for i, hash := range hashes {
+		counts, err := source.Range(context.Background(), hash[:5])
+		if err != nil || counts[hash[5:]] != i+1 {
+			t.Fatalf("%s: Range = %v, %v", hash, counts, err)
+		}
+	}
+	if count, err := breached.Count(context.Background(), "letmein"); err != nil || count == 0 {
+		t.Fatalf("letmein: %d, %v", count, err)
+	}
+	if count, err := breached.Count(context.Background(), "correct horse battery"); err != nil || count != 0 {
+		t.Fatalf("not breached: %d, %v", count, err)
+	}
+	// before the first and after the last line
+	for _, prefix := range []string{"00000", "FFFFF"} {
+		if counts, err := source.Range(context.Background(), prefix); err != nil || len(counts) != 0 {
+			t.Errorf("%s: Range = %v, %v", prefix, counts, err)
+		}
+	}
+}
+
+func TestHTTPBreachedSource(t *testing.T) {
+	hash := passwordSHA1("password")
+	var gotPath, gotPadding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotPadding = r.URL.Path, r.Header.Get("Add-Padding")
+		if r.URL.Path == "/range/00000" {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:3730471\r\n00D4F6E8FA6EECAD2A3AA415EEC418D38EC:0\r\n", hash[5:])
+	}))
+	defer server.Close()
+
+	source := &HTTPBreachedSource{URL: server.URL + "/range/"}
+	breached := &BreachedPasswords{Source: source}
+	count, err := breached.Count(context.Background(), "password")
+	if err != nil || count != 3730471 {
+		t.Fatalf("Count = %d, %v", count, err)
+	}
+	if gotPath != "/range/"+hash[:5] || gotPadding != "true" {
+		t.Fatalf("unexpected request %s, padding %q", gotPath, gotPadding)
+	}
+
+	counts, _ := source.Range(context.Background(), hash[:5])
+	if _, ok := counts["00D4F6E8FA6EECAD2A3AA415EEC418D38EC"]; ok || len(counts) != 2 {
+		t.Fatalf("the padding lines should be skipped: %v", counts)
+	}
+	if _, err := source.Range(context.Background(), "00000"); err == nil {
+		t.Fatal("expected an error for a failed request")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := breached.Count(ctx, "password"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the ctx error, got %v", err)
+	}
+}
+
+func TestPasswordPolicy_Breached(t *testing.T) {
+	source := NewMemoryBreachedSource()
+	source.Add("correct horse battery", 5)
+	policy := PasswordPolicy{Breached: &BreachedPasswords{Source: source, Threshold: 5}}
+
+	var policyErr *PasswordPolicyError
+	if err := policy.Validate("correct horse battery"); !errors.As(err, &policyErr) || !policyErr.Has(PasswordBreached) {
+		t.Fatalf("expected PasswordBreached, got %v", err)
+	}
+	if err := policy.Validate("correct horse stapler"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	policy.Breached.Threshold = 6
+	if err := policy.Validate("correct horse battery"); err != nil {
+		t.Fatalf("below the threshold: %v", err)
+	}
+
+	policy.Breached.Source = &HTTPBreachedSource{URL: "http://127.0.0.1:1/range/"}
+	err := policy.Validate("correct horse battery")
+	if !errors.As(err, &policyErr) || policyErr.BreachedErr == nil || len(policyErr.Violations) != 0 {
+		t.Fatalf("expected the source error, got %v", err)
+	}
+}
+
+type countingBreachedSource struct {
+	calls int
+}
+
+func (s *countingBreachedSource) Range(ctx context.Context, prefix string) (map[string]int, error) {
+	s.calls++
+	return nil, errors.New("source down")
+}
+
+func TestPasswordPolicy_BreachedSkipped(t *testing.T) {
+	source := &countingBreachedSource{}
+	policy := PasswordPolicy{Breached: &BreachedPasswords{Source: source}}
+
+	// the cheap rules already fail, the source is not queried
+	var policyErr *PasswordPolicyError
+	if err := policy.Validate("short"); !errors.As(err, &policyErr) || !policyErr.Has(PasswordTooShort) || policyErr.BreachedErr != nil {
+		t.Fatalf("expected PasswordTooShort only, got %v", err)
+	}
+	if source.calls != 0 {
+		t.Fatalf("the source was queried %d times", source.calls)
+	}
+
+	err := policy.Validate("correct horse battery")
+	if !errors.As(err, &policyErr) || source.calls != 1 || !strings.Contains(err.Error(), "source down") {
+		t.Fatalf("expected the source error, got %v", err)
+	}
+}
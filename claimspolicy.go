@@ -0,0 +1,103 @@
+package gohelpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+ClaimsValidator is a custom check of the claim values, set it in `ParseOptions.Validators`. A returned error rejects the token
+with `ErrTokenBadClaims`. The claims are decoded with `json.Number`, thus numbers keep their precision:
+
+	func(claims jwt.MapClaims) error {
+		if claims["tenant_id"] != "acme" {
+			return errors.New("unknown tenant")
+		}
+		return nil
+	}
+*/
+type ClaimsValidator func(claims jwt.MapClaims) error
+
+// checkPolicy runs the checks the jwt parser doesn't cover: issuers, required claims, max age and the validators.
+func (v *Verifier) checkPolicy(token *jwt.Token, leeway time.Duration) error {
+	opts := v.opts
+	if len(v.issuers) == 0 && len(opts.RequiredClaims) == 0 && opts.MaxAge == 0 && len(opts.Validators) == 0 {
+		return nil
+	}
+
+	claims, err := v.rawClaims(token)
+	if err != nil {
+		return &TokenError{Kind: TokenMalformed, Err: err}
+	}
+
+	if len(v.issuers) > 0 {
+		iss, _ := claims.GetIssuer()
+		if !InSlice(iss, v.issuers) {
+			return &TokenError{Kind: TokenBadIssuer, Err: fmt.Errorf("issuer %q is not accepted", iss)}
+		}
+	}
+
+	for _, name := range opts.RequiredClaims {
+		if value, ok := claims[name]; !ok || value == nil || value == "" {
+			return &TokenError{Kind: TokenMissingClaim, Err: fmt.Errorf("claim %q is required", name)}
+		}
+	}
+
+	if opts.MaxAge > 0 {
+		iat, err := claims.GetIssuedAt()
+		if err != nil || iat == nil {
+			return &TokenError{Kind: TokenMissingClaim, Err: errors.New(`claim "iat" is required`)}
+		}
+		if age := v.clock.Now().Sub(iat.Time); age > opts.MaxAge+leeway {
+			return &TokenError{Kind: TokenTooOld, Err: fmt.Errorf("token issued %v ago, max age is %v", age.Round(time.Second), opts.MaxAge)}
+		}
+	}
+
+	for _, validate := range opts.Validators {
+		if err := validate(claims); err != nil {
+			return &TokenError{Kind: TokenBadClaims, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// rawClaims decodes the payload segment of a parsed token, whatever claims type it was parsed into.
+func (v *Verifier) rawClaims(token *jwt.Token) (jwt.MapClaims, error) {
+	parts := strings.Split(token.Raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token contains an invalid number of segments")
+	}
+
+	payload, err := v.parser.DecodeSegment(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func appendNonEmpty(list []string, values ...string) []string {
+	out := make([]string, 0, len(list)+len(values))
+	for _, group := range [][]string{list, values} {
+		for _, v := range group {
+			if v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package gohelpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestClaimsPolicy(t *testing.T) {
+	secret := []byte("s3cr3t")
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	sign := func(claims jwt.MapClaims) string {
+		tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		return tok
+	}
+	now := clock.Now()
+	full := jwt.MapClaims{"sub": "42", "iss": "b.local", "aud": "web", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix(), "tenant": "acme"}
+
+	opts := ParseOptions{
+		Secret:         secret,
+		Clock:          clock,
+		Issuers:        []string{"a.local", "b.local"},
+		Audiences:      []string{"api", "web"},
+		RequiredClaims: []string{"sub"},
+		RequireExp:     true,
+		MaxAge:         30 * time.Minute,
+		Validators: []ClaimsValidator{func(claims jwt.MapClaims) error {
+			if claims["tenant"] != "acme" {
+				return errors.New("unknown tenant")
+			}
+			return nil
+		}},
+	}
+	verifier, _ := NewVerifier(opts)
+
+	without := func(key string) jwt.MapClaims {
+		c := jwt.MapClaims{}
+		for k, v := range full {
+			if k != key {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		c := without(key)
+		c[key] = value
+		return c
+	}
+
+	cases := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   error
+	}{
+		{"valid", full, nil},
+		{"missing sub", without("sub"), ErrTokenMissingClaim},
+		{"missing exp", without("exp"), ErrTokenMissingClaim},
+		{"missing iat with max age", without("iat"), ErrTokenMissingClaim},
+		{"too old", with("iat", now.Add(-time.Hour).Unix()), ErrTokenTooOld},
+		{"unknown issuer", with("iss", "c.local"), ErrTokenBadIssuer},
+		{"unknown audience", with("aud", "mobile"), ErrTokenBadAudience},
+		{"validator", with("tenant", "umbrella"), ErrTokenBadClaims},
+	}
+
+	for _, c := range cases {
+		_, err := verifier.Verify(sign(c.claims))
+		if c.want == nil && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if c.want != nil && !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	// typed claims go through the same policy
+	if _, err := VerifyClaims[jwtCustomClaims](verifier, sign(without("sub"))); !errors.Is(err, ErrTokenMissingClaim) {
+		t.Errorf("VerifyClaims: got %v, want ErrTokenMissingClaim", err)
+	}
+}
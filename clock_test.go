@@ -0,0 +1,40 @@
+package gohelpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeClock_ExpiryAndNotBefore(t *testing.T) {
+	secret := []byte("s3cr3t")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	tok, _ := NewToken(WithSecret(secret), WithClock(clock), WithTTL(time.Minute), WithNotBefore(start.Add(time.Hour)))
+	verifier, _ := NewVerifier(ParseOptions{Secret: secret, Clock: clock, Leeway: 10 * time.Second})
+
+	steps := []struct {
+		at   time.Time
+		want error
+	}{
+		{start, ErrTokenNotYetValid},
+		{start.Add(time.Hour), ErrTokenExpired}, // nbf reached, but exp passed long ago
+	}
+	for _, s := range steps {
+		clock.Set(s.at)
+		if _, err := verifier.Verify(tok); !errors.Is(err, s.want) {
+			t.Fatalf("at %v: got %v, want %v", s.at, err, s.want)
+		}
+	}
+
+	tok, _ = NewToken(WithSecret(secret), WithClock(clock), WithTTL(time.Minute))
+	clock.Advance(time.Minute + 5*time.Second) // within the leeway
+	if ok, err := verifier.Verify(tok); !ok {
+		t.Fatalf("token within the leeway should be valid, got %v", err)
+	}
+	clock.Advance(10 * time.Second)
+	if _, err := verifier.Verify(tok); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("token beyond the leeway should be expired, got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mbougarne/gohelpers"
+)
+
+func (c *cli) env(args []string) int {
+	if len(args) == 0 {
+		return c.fail(exitUsage, "env: missing subcommand, one of: lint, diff, check")
+	}
+
+	switch args[0] {
+	case "lint":
+		return c.envLint(args[1:])
+	case "diff":
+		return c.envDiff(args[1:])
+	case "check":
+		return c.envCheck(args[1:])
+	default:
+		return c.fail(exitUsage, "env: unknown subcommand %q", args[0])
+	}
+}
+
+// envLint reports the lines `LoadDotEnvToOsEnv` would skip or load differently than expected, one per line as file:line: message.
+func (c *cli) envLint(args []string) int {
+	fs := c.flagSet("env lint")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	file := envFileArg(fs.Args())
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return c.fail(exitError, "env lint: %v", err)
+	}
+
+	code := exitOK
+	for _, issue := range lintDotEnv(data) {
+		fmt.Fprintf(c.stdout, "%s:%s\n", file, issue)
+		if !issue.warning {
+			code = exitError
+		}
+	}
+	return code
+}
+
+// envDiff lists the keys of the example file missing in the dotenv file, and the extra ones. Only missing keys fail.
+func (c *cli) envDiff(args []string) int {
+	fs := c.flagSet("env diff")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		return c.fail(exitUsage, "env diff: expected 2 files, e.g. env diff .env .env.example")
+	}
+
+	vars, code := c.readDotEnv(fs.Arg(0))
+	if code != exitOK {
+		return code
+	}
+	example, code := c.readDotEnv(fs.Arg(1))
+	if code != exitOK {
+		return code
+	}
+
+	missing, extra := missingKeys(vars, sortedKeys(example)), missingKeys(example, sortedKeys(vars))
+	for _, key := range missing {
+		fmt.Fprintf(c.stdout, "missing %s\n", key)
+	}
+	for _, key := range extra {
+		fmt.Fprintf(c.stdout, "extra %s\n", key)
+	}
+	if len(missing) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// envCheck fails when a required key is missing or empty, in the dotenv file and the environment.
+func (c *cli) envCheck(args []string) int {
+	fs := c.flagSet("env check")
+	require := fs.String("require", "", "comma separated keys that must be set and not empty")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *require == "" {
+		return c.fail(exitUsage, "env check: --require is required")
+	}
+
+	// as `LoadDotEnvToOsEnv`, the environment wins over the file, and a missing default .env is fine
+	file := envFileArg(fs.Args())
+	var vars map[string]string
+	if data, err := os.ReadFile(file); err == nil {
+		vars = gohelpers.ParseDotEnv(data)
+	} else if fs.NArg() > 0 || !os.IsNotExist(err) {
+		return c.fail(exitError, "env check: %v", err)
+	}
+
+	var missing []string
+	for _, key := range strings.Split(*require, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			value = vars[key]
+		}
+		if value == "" {
+			missing = append(missing, key)
+		}
+	}
+
+	for _, key := range missing {
+		fmt.Fprintf(c.stdout, "missing %s\n", key)
+	}
+	if len(missing) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+func (c *cli) readDotEnv(file string) (map[string]string, int) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, c.fail(exitError, "%v", err)
+	}
+	return gohelpers.ParseDotEnv(data), exitOK
+}
+
+// lintIssue is a finding of `lintDotEnv`, the warnings don't fail the lint.
+type lintIssue struct {
+	line    int
+	message string
+	warning bool
+}
+
+func (i lintIssue) String() string {
+	if i.warning {
+		return fmt.Sprintf("%d: warning: %s", i.line, i.message)
+	}
+	return fmt.Sprintf("%d: %s", i.line, i.message)
+}
+
+// lintDotEnv checks the lines as `gohelpers.ParseDotEnvLines` sees them, thus as they are loaded at runtime.
+func lintDotEnv(data []byte) []lintIssue {
+	var issues []lintIssue
+	seen := map[string]int{}
+
+	for _, line := range gohelpers.ParseDotEnvLines(data) {
+		report := func(format string, args ...interface{}) {
+			issues = append(issues, lintIssue{line: line.Number, message: fmt.Sprintf(format, args...)})
+		}
+		warn := func(format string, args ...interface{}) {
+			issues = append(issues, lintIssue{line: line.Number, message: fmt.Sprintf(format, args...), warning: true})
+		}
+
+		if line.Skip {
+			continue
+		}
+		if line.Malformed() {
+			report("malformed line, expected KEY=VALUE, it's skipped")
+			continue
+		}
+
+		if line.Key == "" {
+			report("empty key, the value can't be set in the environment")
+			continue
+		}
+		if key := strings.TrimSpace(line.Key); key != line.Key {
+			report("key %q has surrounding spaces, it's loaded as is", line.Key)
+		}
+		if first, ok := seen[line.Key]; ok {
+			report("duplicate key %s, first set on line %d, the last one wins", line.Key, first)
+		} else {
+			seen[line.Key] = line.Number
+		}
+
+		// the loader keeps the values as they are, quotes, spaces and \r included
+		value, crlf := strings.CutSuffix(line.Value, "\r")
+		if crlf {
+			report("CRLF line ending, the \\r is loaded as part of the value of %s", line.Key)
+		}
+		if isQuoted(value) {
+			report("value of %s is quoted, the quotes are loaded as part of the value", line.Key)
+		} else if strings.ContainsAny(strings.TrimSpace(value), " \t") {
+			warn("unquoted value of %s contains spaces, shells and other dotenv tools would split it", line.Key)
+		}
+		if trimmed := strings.TrimSpace(value); trimmed != value {
+			report("value of %s has surrounding spaces, they're loaded as part of the value", line.Key)
+		}
+	}
+
+	return issues
+}
+
+func isQuoted(value string) bool {
+	if len(value) < 2 {
+		return false
+	}
+	quote := value[0]
+	return (quote == '"' || quote == '\'') && value[len(value)-1] == quote
+}
+
+func envFileArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return ".env"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// missingKeys returns the keys not in m.
+func missingKeys(m map[string]string, keys []string) []string {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := m[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
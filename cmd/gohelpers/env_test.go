@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeEnvFile(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestEnvLint(t *testing.T) {
+	file := writeEnvFile(t, ".env", "# comment\nAPP_NAME=\"My App\"\nGREETING=hello world \nNO_EQUAL_SIGN\nPORT=80\nPORT=8080\n=no_key\nEMPTY=\n")
+
+	code, out, _ := runCLI(t, "", "env", "lint", file)
+	if code != exitError {
+		t.Fatalf("env lint = %d, want %d: %s", code, exitError, out)
+	}
+	for _, want := range []string{":2: value of APP_NAME is quoted", ":3: value of GREETING has surrounding spaces", ":3: warning: unquoted value of GREETING", ":4: malformed line", ":6: duplicate key PORT, first set on line 5", ":7: empty key"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if lines := strings.Count(out, "\n"); lines != 6 {
+		t.Errorf("expected 6 issues, got %d:\n%s", lines, out)
+	}
+
+	// the \r of a CRLF file is loaded, thus it's reported
+	crlf := writeEnvFile(t, ".env", "SECRET_KEY=abc\r\nNAME=John\r\n")
+	if code, out, _ := runCLI(t, "", "env", "lint", crlf); code != exitError || strings.Count(out, "CRLF line ending") != 2 {
+		t.Fatalf("CRLF file: %d %s", code, out)
+	}
+
+	// unquoted values with spaces are only a warning
+	spaced := writeEnvFile(t, ".env", "SECRET_KEY=abc\nNAME=John Doe\n")
+	if code, out, _ := runCLI(t, "", "env", "lint", spaced); code != exitOK || !strings.Contains(out, ":2: warning: unquoted value of NAME contains spaces") {
+		t.Fatalf("unquoted spaces: %d %s", code, out)
+	}
+
+	clean := writeEnvFile(t, ".env", "SECRET_KEY=abc\nNAME=John\n")
+	if code, out, _ := runCLI(t, "", "env", "lint", clean); code != exitOK || out != "" {
+		t.Fatalf("clean file: %d %s", code, out)
+	}
+}
+
+func TestEnvDiff(t *testing.T) {
+	env := writeEnvFile(t, ".env", "SECRET_KEY=abc\nDEBUG=1\n")
+	example := writeEnvFile(t, ".env.example", "SECRET_KEY=\nMY_DB_URL=\n")
+
+	code, out, _ := runCLI(t, "", "env", "diff", env, example)
+	if code != exitError || out != "missing MY_DB_URL\nextra DEBUG\n" {
+		t.Fatalf("env diff = %d, %q", code, out)
+	}
+
+	if code, out, _ := runCLI(t, "", "env", "diff", example, env); code != exitError || !strings.Contains(out, "missing DEBUG") {
+		t.Fatalf("reversed env diff = %d, %q", code, out)
+	}
+	if code, _, _ := runCLI(t, "", "env", "diff", env); code != exitUsage {
+		t.Fatalf("one file: got %d, want %d", code, exitUsage)
+	}
+}
+
+func TestEnvCheck(t *testing.T) {
+	file := writeEnvFile(t, ".env", "SECRET_KEY=abc\nEMPTY_KEY=\n")
+	t.Setenv("CLI_FROM_OS", "1")
+
+	if code, out, _ := runCLI(t, "", "env", "check", "--require", "SECRET_KEY, CLI_FROM_OS", file); code != exitOK {
+		t.Fatalf("env check = %d, %s", code, out)
+	}
+
+	code, out, _ := runCLI(t, "", "env", "check", "--require", "SECRET_KEY,EMPTY_KEY,UNKNOWN_KEY", file)
+	if code != exitError || out != "missing EMPTY_KEY\nmissing UNKNOWN_KEY\n" {
+		t.Fatalf("env check = %d, %q", code, out)
+	}
+
+	if code, _, _ := runCLI(t, "", "env", "check", file); code != exitUsage {
+		t.Fatalf("without --require: got %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mbougarne/gohelpers"
+)
+
+// clock of the jwt commands, a `gohelpers.FakeClock` in the tests.
+var clock gohelpers.Clock = gohelpers.SystemClock
+
+func (c *cli) jwt(args []string) int {
+	if len(args) == 0 {
+		return c.fail(exitUsage, "jwt: missing subcommand, one of: sign, verify, decode")
+	}
+
+	switch args[0] {
+	case "sign":
+		return c.jwtSign(args[1:])
+	case "verify":
+		return c.jwtVerify(args[1:])
+	case "decode":
+		return c.jwtDecode(args[1:])
+	default:
+		return c.fail(exitUsage, "jwt: unknown subcommand %q", args[0])
+	}
+}
+
+func (c *cli) jwtSign(args []string) int {
+	fs := c.flagSet("jwt sign")
+	claimsFile := fs.String("claims", "", `JSON file of the claims, "-" reads stdin`)
+	secretEnv := fs.String("secret-env", "SECRET_KEY", "env var of the signing secret")
+	envFile := fs.String("env-file", ".env", "dotenv file loaded before reading the secret, if it exists")
+	ttl := fs.Duration("ttl", 0, "token lifetime, sets exp, a negative ttl mints an expired token (default: exp of the claims, or 12m)")
+	subject := fs.String("sub", "", "sets the sub claim")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	secret, code := c.secretFromEnv(*envFile, *secretEnv)
+	if code != exitOK {
+		return code
+	}
+
+	claims := jwt.MapClaims{}
+	if *claimsFile != "" {
+		data, err := c.readInput(*claimsFile)
+		if err != nil {
+			return c.fail(exitError, "jwt sign: %v", err)
+		}
+		if err := json.Unmarshal(data, &claims); err != nil {
+			return c.fail(exitError, "jwt sign: %s is not a JSON object: %v", *claimsFile, err)
+		}
+	}
+	if *ttl != 0 {
+		claims["exp"] = clock.Now().Add(*ttl).Unix()
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = clock.Now().Add(gohelpers.DefaultTokenTTL).Unix()
+	}
+	if *subject != "" {
+		claims["sub"] = *subject
+	}
+
+	token, err := gohelpers.GenerateJwtTokenWithClock(secret, clock, claims)
+	if err != nil {
+		return c.fail(exitError, "jwt sign: %v", err)
+	}
+
+	fmt.Fprintln(c.stdout, token)
+	return exitOK
+}
+
+func (c *cli) jwtVerify(args []string) int {
+	fs := c.flagSet("jwt verify")
+	secretEnv := fs.String("secret-env", "SECRET_KEY", "env var of the signing secret")
+	envFile := fs.String("env-file", ".env", "dotenv file loaded before reading the secret, if it exists")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	token, err := c.tokenArg(fs.Args())
+	if err != nil {
+		return c.fail(exitUsage, "jwt verify: %v", err)
+	}
+	secret, code := c.secretFromEnv(*envFile, *secretEnv)
+	if code != exitOK {
+		return code
+	}
+
+	if _, err := gohelpers.VerifyJwtToken(token, secret); err != nil {
+		code, kind := tokenErrorCode(err)
+		c.printJSON(map[string]interface{}{"valid": false, "error": kind, "message": err.Error()})
+		return code
+	}
+
+	claims, _ := gohelpers.GetClaims(token, secret)
+	c.printJSON(map[string]interface{}{"valid": true, "claims": claims})
+	return exitOK
+}
+
+func (c *cli) jwtDecode(args []string) int {
+	fs := c.flagSet("jwt decode")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	token, err := c.tokenArg(fs.Args())
+	if err != nil {
+		return c.fail(exitUsage, "jwt decode: %v", err)
+	}
+
+	info, err := gohelpers.InspectToken(token, clock)
+	if err != nil {
+		code, kind := tokenErrorCode(err)
+		c.printJSON(map[string]interface{}{"verified": false, "error": kind, "problems": info.Problems})
+		return code
+	}
+
+	out := map[string]interface{}{"inspection": info}
+	if info.ExpiresAt != nil {
+		out["expires_in"] = info.ExpiresIn.Round(time.Second).String()
+	}
+	c.printJSON(out)
+	return exitOK
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// secretFromEnv loads the dotenv file when it exists, and reads the secret with `GenerateSecretKey`.
+func (c *cli) secretFromEnv(envFile, name string) ([]byte, int) {
+	if envFile != "" {
+		if _, err := os.Stat(envFile); err == nil {
+			if err := gohelpers.LoadDotEnvToOsEnv(envFile); err != nil {
+				return nil, c.fail(exitError, "cannot load %s: %v", envFile, err)
+			}
+		}
+	}
+
+	secret, err := gohelpers.GenerateSecretKey(name, true)
+	if err != nil {
+		return nil, c.fail(exitError, "%v", err)
+	}
+	return secret, exitOK
+}
+
+// tokenArg returns the token of the first argument, or of stdin when there's no argument or it is "-".
+func (c *cli) tokenArg(args []string) (string, error) {
+	name := "-"
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if name != "-" {
+		return strings.TrimSpace(name), nil
+	}
+
+	data, err := c.readInput("-")
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errors.New("missing token, pass it as argument or on stdin")
+	}
+	return token, nil
+}
+
+func (c *cli) readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// tokenErrorCode maps a token error to its exit code and a short machine readable kind.
+func tokenErrorCode(err error) (int, string) {
+	var tokenErr *gohelpers.TokenError
+	if !errors.As(err, &tokenErr) {
+		return exitError, "error"
+	}
+
+	switch tokenErr.Kind {
+	case gohelpers.TokenExpired:
+		return exitExpired, "expired"
+	case gohelpers.TokenBadSignature:
+		return exitBadSig, "bad_signature"
+	case gohelpers.TokenMalformed:
+		return exitMalformed, "malformed"
+	case gohelpers.TokenMisconfigured:
+		return exitError, "misconfigured"
+	default:
+		return exitInvalidJWT, strings.ReplaceAll(tokenErr.Kind.String(), " ", "_")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mbougarne/gohelpers"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+const keyTypeHMAC = "hmac"
+
+func (c *cli) keygen(args []string) int {
+	fs := c.flagSet("keygen")
+	keyType := fs.String("type", keyTypeHMAC, "key type, one of: hmac, ed25519, ecdsa, rsa")
+	size := fs.Int("bytes", 64, "size of an hmac secret")
+	format := fs.String("format", "base64", "encoding of an hmac secret, one of: base64, base64url, hex (signing keys are PEM)")
+	out := fs.String("out", "", "file of the key, written with 0600 permissions (default: stdout)")
+	pubOut := fs.String("pub-out", "", "file of the public key of a signing key (default: stdout, after the private key when --out is not set)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if *keyType == keyTypeHMAC && set["pub-out"] {
+		return c.fail(exitUsage, "keygen: --pub-out applies to signing keys only, an hmac secret has no public key")
+	}
+	if *keyType != keyTypeHMAC && (set["bytes"] || set["format"]) {
+		return c.fail(exitUsage, "keygen: --bytes and --format apply to hmac secrets only, %s keys are PEM", *keyType)
+	}
+
+	var private, public []byte
+	if *keyType == keyTypeHMAC {
+		encode, ok := secretEncodings[*format]
+		if !ok {
+			return c.fail(exitUsage, "keygen: unknown format %q, one of: base64, base64url, hex", *format)
+		}
+		secret, err := gohelpers.GenerateRandomKey(*size)
+		if err != nil {
+			return c.fail(exitUsage, "keygen: %v", err)
+		}
+		private = []byte(encode(secret) + "\n")
+	} else {
+		key, _, err := gohelpers.GenerateSigningKey(*keyType)
+		if err != nil {
+			return c.fail(exitUsage, "keygen: %v", err)
+		}
+		if private, err = gohelpers.MarshalPrivateKeyPEM(key); err != nil {
+			return c.fail(exitError, "keygen: %v", err)
+		}
+		if public, err = gohelpers.MarshalPublicKeyPEM(key.Public()); err != nil {
+			return c.fail(exitError, "keygen: %v", err)
+		}
+	}
+
+	if *out == "" {
+		c.stdout.Write(private)
+	} else if err := os.WriteFile(*out, private, 0o600); err != nil {
+		return c.fail(exitError, "keygen: %v", err)
+	}
+	if public == nil {
+		return exitOK
+	}
+	if *pubOut == "" {
+		c.stdout.Write(public)
+	} else if err := os.WriteFile(*pubOut, public, 0o644); err != nil {
+		return c.fail(exitError, "keygen: %v", err)
+	}
+	return exitOK
+}
+
+var secretEncodings = map[string]func([]byte) string{
+	"base64":    base64.StdEncoding.EncodeToString,
+	"base64url": base64.RawURLEncoding.EncodeToString,
+	"hex":       hex.EncodeToString,
+}
+
+// hashPassword reads the password from the terminal without echo, or the first line of stdin when it's piped.
+func (c *cli) hashPassword(args []string) int {
+	fs := c.flagSet("hash-password")
+	cost := fs.Int("cost", bcrypt.DefaultCost, "bcrypt cost")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	password, err := c.readPassword()
+	if err != nil {
+		return c.fail(exitError, "hash-password: %v", err)
+	}
+	if password == "" {
+		return c.fail(exitUsage, "hash-password: empty password")
+	}
+
+	// the hasher of the services `HashPassword`, the passwords over 72 bytes are pre-hashed
+	hashed, err := gohelpers.BcryptHasher{Cost: *cost, PreHash: true}.Hash(password)
+	if err != nil {
+		return c.fail(exitUsage, "hash-password: %v", err)
+	}
+
+	fmt.Fprintln(c.stdout, hashed)
+	return exitOK
+}
+
+func (c *cli) readPassword() (string, error) {
+	if f, ok := c.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(c.stderr, "password: ")
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(c.stderr)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprint(c.stderr, "confirm password: ")
+		confirmation, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(c.stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(password) != string(confirmation) {
+			return "", errors.New("the passwords don't match")
+		}
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("missing password on stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mbougarne/gohelpers"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestKeygen_Secret(t *testing.T) {
+	code, out, stderr := runCLI(t, "", "keygen", "--bytes", "64", "--format", "base64")
+	if code != exitOK {
+		t.Fatalf("keygen = %d, %s", code, stderr)
+	}
+	if secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(out)); err != nil || len(secret) != 64 {
+		t.Fatalf("expected 64 base64 encoded bytes, got %q", out)
+	}
+
+	if code, _, _ := runCLI(t, "", "keygen", "--bytes", "8"); code != exitUsage {
+		t.Fatalf("too short secret: got %d, want %d", code, exitUsage)
+	}
+	if code, _, _ := runCLI(t, "", "keygen", "--format", "base32"); code != exitUsage {
+		t.Fatalf("unknown format: got %d, want %d", code, exitUsage)
+	}
+}
+
+func TestKeygen_SigningKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+
+	code, out, stderr := runCLI(t, "", "keygen", "--type", "ed25519", "--out", keyFile)
+	if code != exitOK {
+		t.Fatalf("keygen = %d, %s", code, stderr)
+	}
+
+	info, err := os.Stat(keyFile)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("the key file should be written with 0600: %v %v", info, err)
+	}
+	data, _ := os.ReadFile(keyFile)
+	key, err := gohelpers.ParsePrivateKeyPEM(data)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM: %v", err)
+	}
+	if _, err := gohelpers.ParsePublicKeyPEM([]byte(out)); err != nil || key == nil {
+		t.Fatalf("expected the public key on stdout, got %q: %v", out, err)
+	}
+}
+
+func TestKeygen_SigningKeyStdout(t *testing.T) {
+	code, out, stderr := runCLI(t, "", "keygen", "--type", "ecdsa")
+	if code != exitOK {
+		t.Fatalf("keygen = %d, %s", code, stderr)
+	}
+	private, rest := pem.Decode([]byte(out))
+	public, _ := pem.Decode(rest)
+	if private == nil || private.Type != "PRIVATE KEY" || public == nil || public.Type != "PUBLIC KEY" {
+		t.Fatalf("expected the private then the public key on stdout, got %q", out)
+	}
+
+	for _, args := range [][]string{
+		{"--type", "ed25519", "--bytes", "32"},
+		{"--type", "rsa", "--format", "hex"},
+		{"--pub-out", filepath.Join(t.TempDir(), "pub.pem")},
+	} {
+		if code, _, _ := runCLI(t, "", append([]string{"keygen"}, args...)...); code != exitUsage {
+			t.Errorf("%v: got %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+func TestHashPasswordCommand(t *testing.T) {
+	code, out, stderr := runCLI(t, "s3cr3t\n", "hash-password", "--cost", "4")
+	hashed := strings.TrimSpace(out)
+	if code != exitOK || !gohelpers.VerifyHashedPassword("s3cr3t", hashed) {
+		t.Fatalf("hash-password = %d, %q, %s", code, out, stderr)
+	}
+	if cost, _ := bcrypt.Cost([]byte(hashed)); cost != 4 {
+		t.Fatalf("got cost %d, want 4", cost)
+	}
+
+	// a passphrase over the 72 bytes of bcrypt, pre-hashed like the services do
+	long := strings.Repeat("correct horse battery staple ", 4)
+	code, out, stderr = runCLI(t, long+"\n", "hash-password", "--cost", "4")
+	hashed = strings.TrimSpace(out)
+	if code != exitOK || !gohelpers.VerifyHashedPassword(long, hashed) || gohelpers.VerifyHashedPassword(long[:72], hashed) {
+		t.Fatalf("long password: %d, %q, %s", code, out, stderr)
+	}
+
+	if code, _, _ := runCLI(t, "", "hash-password"); code == exitOK {
+		t.Fatal("expected a failure without a password")
+	}
+	if code, _, _ := runCLI(t, "s3cr3t\n", "hash-password", "--cost", "99"); code != exitUsage {
+		t.Fatalf("bad cost: got %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Command gohelpers exposes the gohelpers package to the shell, e.g. to mint test tokens without writing Go:
+
+	gohelpers jwt sign --claims claims.json --secret-env SECRET_KEY --ttl 1h
+	gohelpers jwt verify <token>
+	gohelpers jwt decode <token>
+	gohelpers env lint .env
+	gohelpers env diff .env .env.example
+	gohelpers env check --require SECRET_KEY,MY_DB_URL
+	gohelpers keygen --bytes 64 --format base64
+	gohelpers keygen --type ed25519 --out key.pem
+	gohelpers hash-password --cost 12
+
+Run `gohelpers help` for the list of commands and exit codes.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes, scripts could use them to tell why a command failed.
+const (
+	exitOK         = 0
+	exitError      = 1 // any other failure
+	exitUsage      = 2 // bad command or flags
+	exitExpired    = 3 // the token is expired
+	exitBadSig     = 4 // the token signature doesn't match the secret
+	exitMalformed  = 5 // the token is not a JWT
+	exitInvalidJWT = 6 // the token is rejected for another reason (not valid yet, bad method...)
+)
+
+const usageText = `usage: gohelpers <command> [flags]
+
+commands:
+  jwt sign     sign the claims of a JSON file with the secret of an env var
+  jwt verify   verify a token (argument or stdin)
+  jwt decode   decode a token WITHOUT verifying it
+  env lint     report malformed lines, duplicate keys, CRLF endings, quoted or space-padded values of a dotenv file
+               (default .env), and warn on unquoted values with spaces
+  env diff     list the keys missing in a dotenv file, and the extra ones, compared to an example file
+  env check    fail when a --require key is missing or empty, in the dotenv file (default .env) or the environment
+  keygen       generate an hmac secret (default 64 bytes, base64), or a signing key as PEM with --type ed25519|ecdsa|rsa
+  hash-password  hash a password with bcrypt (--cost), read from the terminal without echo or from stdin
+
+exit codes:
+  0 ok, 1 error (env: lint issues, missing keys), 2 usage
+  3 expired token, 4 bad signature, 5 malformed token, 6 invalid token
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli holds the streams of a run, thus the commands are testable.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		c.usage()
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "jwt":
+		return c.jwt(args[1:])
+	case "env":
+		return c.env(args[1:])
+	case "keygen":
+		return c.keygen(args[1:])
+	case "hash-password":
+		return c.hashPassword(args[1:])
+	case "help", "-h", "--help":
+		c.usage()
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "gohelpers: unknown command %q\n", args[0])
+		c.usage()
+		return exitUsage
+	}
+}
+
+func (c *cli) usage() {
+	fmt.Fprint(c.stderr, usageText)
+}
+
+func (c *cli) printJSON(v interface{}) {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func (c *cli) fail(code int, format string, args ...interface{}) int {
+	fmt.Fprintf(c.stderr, "gohelpers: "+format+"\n", args...)
+	return code
+}
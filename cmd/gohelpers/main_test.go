@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbougarne/gohelpers"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestJwtSignVerifyDecode(t *testing.T) {
+	t.Setenv("CLI_TEST_SECRET", "s3cr3t")
+	// stopped on a whole second, thus the exp of the token is exactly 1h ahead when decoding
+	defer func(old gohelpers.Clock) { clock = old }(clock)
+	clock = gohelpers.NewFakeClock(time.Now().Truncate(time.Second))
+	claimsFile := filepath.Join(t.TempDir(), "claims.json")
+	os.WriteFile(claimsFile, []byte(`{"username": "johnDoe"}`), 0o600)
+
+	code, token, stderr := runCLI(t, "", "jwt", "sign", "--claims", claimsFile, "--secret-env", "CLI_TEST_SECRET", "--ttl", "1h", "--sub", "42")
+	token = strings.TrimSpace(token)
+	if code != exitOK || token == "" {
+		t.Fatalf("jwt sign = %d, %q", code, stderr)
+	}
+
+	code, out, _ := runCLI(t, token, "jwt", "verify", "--secret-env", "CLI_TEST_SECRET")
+	var verified struct {
+		Valid  bool                   `json:"valid"`
+		Claims map[string]interface{} `json:"claims"`
+	}
+	if err := json.Unmarshal([]byte(out), &verified); err != nil || code != exitOK || !verified.Valid {
+		t.Fatalf("jwt verify = %d, %s", code, out)
+	}
+	if verified.Claims["username"] != "johnDoe" || verified.Claims["sub"] != "42" {
+		t.Fatalf("unexpected claims: %v", verified.Claims)
+	}
+
+	code, out, _ = runCLI(t, "", "jwt", "decode", token)
+	var decoded struct {
+		ExpiresIn string `json:"expires_in"`
+	}
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("jwt decode output is not JSON: %v, %s", err, out)
+	}
+	if code != exitOK || !strings.Contains(out, `"verified": false`) || decoded.ExpiresIn != "1h0m0s" {
+		t.Fatalf("jwt decode = %d, %s", code, out)
+	}
+}
+
+func TestJwtVerify_ExitCodes(t *testing.T) {
+	t.Setenv("CLI_TEST_SECRET", "s3cr3t")
+	t.Setenv("CLI_OTHER_SECRET", "other")
+
+	_, expired, _ := runCLI(t, "", "jwt", "sign", "--secret-env", "CLI_TEST_SECRET", "--ttl", "-1h")
+	_, valid, _ := runCLI(t, "", "jwt", "sign", "--secret-env", "CLI_TEST_SECRET")
+
+	cases := []struct {
+		name   string
+		token  string
+		secret string
+		want   int
+	}{
+		{"expired", expired, "CLI_TEST_SECRET", exitExpired},
+		{"bad signature", valid, "CLI_OTHER_SECRET", exitBadSig},
+		{"malformed", "not-a-token", "CLI_TEST_SECRET", exitMalformed},
+		{"missing secret", valid, "CLI_MISSING_SECRET", exitError},
+	}
+
+	for _, c := range cases {
+		if code, out, _ := runCLI(t, "", "jwt", "verify", "--secret-env", c.secret, strings.TrimSpace(c.token)); code != c.want {
+			t.Errorf("%s: got exit code %d, want %d: %s", c.name, code, c.want, out)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, _ := runCLI(t, ""); code != exitUsage {
+		t.Fatalf("no command: got %d, want %d", code, exitUsage)
+	}
+	if code, _, _ := runCLI(t, "", "jwt", "unknown"); code != exitUsage {
+		t.Fatalf("unknown subcommand: got %d, want %d", code, exitUsage)
+	}
+}
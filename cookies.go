@@ -0,0 +1,258 @@
+package gohelpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const hostCookiePrefix = "__Host-"
+
+// ErrCSRFTokenInvalid is passed to `CSRFOptions.ErrorHandler` when the CSRF token is missing or doesn't match.
+var ErrCSRFTokenInvalid = errors.New("invalid CSRF token")
+
+/*
+CookieOptions config for the token cookies. The zero value is the secure default: HttpOnly, Secure, SameSite=Lax,
+Path=/ and the `__Host-` prefix on the default names. Use `AccessCookieName` as `ParseOptions.CookieName`.
+*/
+type CookieOptions struct {
+	Name        string // access token cookie. Default: "__Host-access_token"
+	RefreshName string // refresh token cookie. Default: "__Host-refresh_token"
+	CSRFName    string // CSRF cookie, readable by JS. Default: "__Host-csrf_token"
+
+	// CSRFSecret is the HMAC key of the CSRF token, when set `SetTokenCookies` also writes the CSRF cookie.
+	CSRFSecret []byte
+
+	Domain   string        // setting a domain drops the `__Host-` prefix of the default names
+	Path     string        // default: "/", another path drops the `__Host-` prefix of the default names
+	SameSite http.SameSite // default: http.SameSiteLaxMode
+	Insecure bool          // allow plain http for local development, drops Secure and the `__Host-` prefix
+	Clock    Clock         // clock of the cookies Max-Age, default: SystemClock
+}
+
+// AccessCookieName returns the name of the access token cookie.
+func (o CookieOptions) AccessCookieName() string {
+	return o.cookieName(o.Name, "access_token")
+}
+
+// RefreshCookieName returns the name of the refresh token cookie.
+func (o CookieOptions) RefreshCookieName() string {
+	return o.cookieName(o.RefreshName, "refresh_token")
+}
+
+// CSRFCookieName returns the name of the CSRF cookie.
+func (o CookieOptions) CSRFCookieName() string {
+	return o.cookieName(o.CSRFName, "csrf_token")
+}
+
+/*
+SetTokenCookies writes the access token, and the refresh token when not empty, to HttpOnly cookies.
+The cookies Max-Age is taken from the token exp. When `CSRFSecret` is set, it also writes a CSRF token bound to the
+token subject to a cookie readable by JS, and returns it. The client sends it back in the `X-CSRF-Token` header, see `CSRFProtect`.
+*/
+func SetTokenCookies(w http.ResponseWriter, opts CookieOptions, accessToken, refreshToken string) (string, error) {
+	access := opts.newCookie(opts.AccessCookieName(), accessToken, true)
+	http.SetCookie(w, access)
+	if refreshToken != "" {
+		http.SetCookie(w, opts.newCookie(opts.RefreshCookieName(), refreshToken, true))
+	}
+
+	if len(opts.CSRFSecret) == 0 {
+		return "", nil
+	}
+
+	csrfToken, err := NewCSRFToken(opts.CSRFSecret, unverifiedSubject(accessToken))
+	if err != nil {
+		return "", err
+	}
+	opts.setCSRFCookie(w, access, csrfToken)
+
+	return csrfToken, nil
+}
+
+// setCSRFCookie writes the CSRF cookie with the lifetime of the access cookie, thus they expire together.
+func (o CookieOptions) setCSRFCookie(w http.ResponseWriter, access *http.Cookie, csrfToken string) {
+	csrfCookie := o.newCookie(o.CSRFCookieName(), csrfToken, false)
+	csrfCookie.Expires, csrfCookie.MaxAge = access.Expires, access.MaxAge
+	http.SetCookie(w, csrfCookie)
+}
+
+// ClearTokenCookies expires the access, refresh and CSRF cookies, e.g. on logout.
+func ClearTokenCookies(w http.ResponseWriter, opts CookieOptions) {
+	for _, name := range []string{opts.AccessCookieName(), opts.RefreshCookieName(), opts.CSRFCookieName()} {
+		c := opts.newCookie(name, "", true)
+		c.MaxAge = -1
+		c.Expires = time.Unix(0, 0)
+		http.SetCookie(w, c)
+	}
+}
+
+/*
+NewCSRFToken returns a random CSRF token bound to the binding (e.g. the user id) with an HMAC of the secret:
+"<nonce>.<hmac(nonce, binding)>". A token minted for a user can't be replayed for another one.
+*/
+func NewCSRFToken(secret []byte, binding string) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(nonce)
+
+	return encoded + "." + csrfMac(secret, encoded, binding), nil
+}
+
+// VerifyCSRFToken checks, in constant time, that the token was minted by `NewCSRFToken` with the secret and binding.
+func VerifyCSRFToken(secret []byte, token, binding string) bool {
+	nonce, mac, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(csrfMac(secret, nonce, binding)))
+}
+
+// CSRFOptions config for the `CSRFProtect` middleware.
+type CSRFOptions struct {
+	CookieOptions // CSRFSecret is required
+
+	HeaderName string // header of the CSRF token. Default: "X-CSRF-Token"
+	// ErrorHandler writes the response when the check fails. Default: 403 Forbidden.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+/*
+CSRFProtect returns a double-submit CSRF middleware, mount it after `Authenticate`. For unsafe methods (POST, PUT, PATCH, DELETE...)
+of requests whose token came from a cookie, the CSRF header must equal the CSRF cookie, and be bound to the token subject.
+Requests authenticated with the Authorization header are not exposed to CSRF, they are passed as they are.
+*/
+func CSRFProtect(opts CSRFOptions) func(http.Handler) http.Handler {
+	header := opts.HeaderName
+	if header == "" {
+		header = "X-CSRF-Token"
+	}
+	errorHandler := opts.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if source, _ := TokenSourceFromContext(r.Context()); source != SourceCookie {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sent := r.Header.Get(header)
+			cookie, err := r.Cookie(opts.CSRFCookieName())
+			if len(opts.CSRFSecret) == 0 || sent == "" || err != nil ||
+				subtle.ConstantTimeCompare([]byte(sent), []byte(cookie.Value)) != 1 {
+				errorHandler(w, r, ErrCSRFTokenInvalid)
+				return
+			}
+
+			var subject string
+			if claims, ok := ClaimsFromContext[jwt.MapClaims](r.Context()); ok {
+				subject, _ = claims.GetSubject()
+			}
+			if !VerifyCSRFToken(opts.CSRFSecret, sent, subject) {
+				errorHandler(w, r, ErrCSRFTokenInvalid)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (o CookieOptions) cookieName(name, fallback string) string {
+	if name != "" {
+		return name
+	}
+	if o.Insecure || o.Domain != "" || (o.Path != "" && o.Path != "/") {
+		return fallback
+	}
+	return hostCookiePrefix + fallback
+}
+
+func (o CookieOptions) newCookie(name, value string, httpOnly bool) *http.Cookie {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		HttpOnly: httpOnly,
+		Secure:   !o.Insecure,
+		SameSite: o.SameSite,
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	if strings.HasPrefix(name, hostCookiePrefix) {
+		// browsers reject __Host- cookies that are not Secure, with a Domain, or a Path other than "/"
+		c.Secure, c.Domain, c.Path = true, "", "/"
+	}
+	if exp := unverifiedExpiry(value); !exp.IsZero() {
+		c.Expires = exp
+		c.MaxAge = max(int(exp.Sub(clockOrSystem(o.Clock).Now()).Seconds()), 1)
+	}
+
+	return c
+}
+
+func csrfMac(secret []byte, nonce, binding string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(nonce))
+	mac.Write([]byte{0})
+	mac.Write([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func unverifiedClaims(tokenString string) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil
+	}
+	return claims
+}
+
+func unverifiedExpiry(tokenString string) time.Time {
+	if claims := unverifiedClaims(tokenString); claims != nil {
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			return exp.Time
+		}
+	}
+	return time.Time{}
+}
+
+func unverifiedSubject(tokenString string) string {
+	if claims := unverifiedClaims(tokenString); claims != nil {
+		sub, _ := claims.GetSubject()
+		return sub
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package gohelpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetTokenCookies_Clock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	access, _ := NewToken(WithSecret([]byte("s3cr3t")), WithClock(clock), WithTTL(10*time.Minute))
+	clock.Advance(4 * time.Minute)
+
+	rec := httptest.NewRecorder()
+	SetTokenCookies(rec, CookieOptions{Clock: clock}, access, "")
+	if c := rec.Result().Cookies()[0]; c.MaxAge != 360 {
+		t.Fatalf("Max-Age should follow the clock, got %d", c.MaxAge)
+	}
+}
+
+func TestSetAndClearTokenCookies(t *testing.T) {
+	secret := []byte("s3cr3t")
+	access, _ := NewToken(WithSecret(secret), WithSubject("42"), WithTTL(10*time.Minute))
+
+	rec := httptest.NewRecorder()
+	csrf, err := SetTokenCookies(rec, CookieOptions{CSRFSecret: []byte("csrf-key")}, access, "")
+	if err != nil || csrf == "" {
+		t.Fatalf("SetTokenCookies = %q, %v", csrf, err)
+	}
+
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	a := cookies["__Host-access_token"]
+	if a == nil || !a.HttpOnly || !a.Secure || a.Path != "/" || a.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected access cookie: %+v", a)
+	}
+	if a.MaxAge < 590 || a.MaxAge > 600 {
+		t.Fatalf("access cookie Max-Age should follow exp, got %d", a.MaxAge)
+	}
+	if c := cookies["__Host-csrf_token"]; c == nil || c.HttpOnly || c.Value != csrf {
+		t.Fatalf("unexpected CSRF cookie: %+v", c)
+	}
+
+	rec = httptest.NewRecorder()
+	ClearTokenCookies(rec, CookieOptions{Insecure: true})
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge >= 0 || c.Secure {
+			t.Fatalf("cookie should be cleared, and not secure in insecure mode: %+v", c)
+		}
+	}
+}
+
+func TestCSRFProtect(t *testing.T) {
+	secret := []byte("s3cr3t")
+	cookieOpts := CookieOptions{CSRFSecret: []byte("csrf-key")}
+	access, _ := NewToken(WithSecret(secret), WithSubject("42"))
+	csrf, _ := NewCSRFToken(cookieOpts.CSRFSecret, "42")
+	otherUserCSRF, _ := NewCSRFToken(cookieOpts.CSRFSecret, "43")
+
+	handler := Authenticate(AuthOptions{ParseOptions: ParseOptions{Secret: secret, CookieName: cookieOpts.AccessCookieName()}})(
+		CSRFProtect(CSRFOptions{CookieOptions: cookieOpts})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		),
+	)
+
+	serve := func(method string, fromCookie bool, header, cookie string) int {
+		req := httptest.NewRequest(method, "http://x.local/api", nil)
+		if fromCookie {
+			req.AddCookie(&http.Cookie{Name: cookieOpts.AccessCookieName(), Value: access})
+		} else {
+			req.Header.Set("Authorization", "Bearer "+access)
+		}
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: cookieOpts.CSRFCookieName(), Value: cookie})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	cases := []struct {
+		name       string
+		method     string
+		fromCookie bool
+		header     string
+		cookie     string
+		want       int
+	}{
+		{"safe method", http.MethodGet, true, "", "", http.StatusOK},
+		{"bearer header", http.MethodPost, false, "", "", http.StatusOK},
+		{"cookie without csrf", http.MethodPost, true, "", "", http.StatusForbidden},
+		{"cookie with csrf", http.MethodPost, true, csrf, csrf, http.StatusOK},
+		{"header and cookie differ", http.MethodPost, true, csrf, otherUserCSRF, http.StatusForbidden},
+		{"csrf of another user", http.MethodPost, true, otherUserCSRF, otherUserCSRF, http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		if got := serve(c.method, c.fromCookie, c.header, c.cookie); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSlidingSession_CSRFCookie(t *testing.T) {
+	secret := []byte("s3cr3t")
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	cookieOpts := CookieOptions{CSRFSecret: []byte("csrf-key"), Clock: clock}
+	handler := Authenticate(AuthOptions{
+		ParseOptions: ParseOptions{Secret: secret, Clock: clock, CookieName: cookieOpts.AccessCookieName()},
+		Refresh:      &SlidingSession{Window: 2 * time.Minute, Cookie: &cookieOpts},
+	})(CSRFProtect(CSRFOptions{CookieOptions: cookieOpts})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	// login: both cookies live 10 minutes
+	access, _ := NewToken(WithSecret(secret), WithClock(clock), WithSubject("42"), WithTTL(10*time.Minute))
+	rec := httptest.NewRecorder()
+	csrf, _ := SetTokenCookies(rec, cookieOpts, access, "")
+	jar := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		jar[c.Name] = c
+	}
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://x.local/api", nil)
+		for _, c := range jar {
+			if c.MaxAge > 0 && clock.Now().Before(c.Expires) {
+				req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+			}
+		}
+		req.Header.Set("X-CSRF-Token", csrf)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		for _, c := range rec.Result().Cookies() {
+			jar[c.Name] = c
+		}
+		return rec
+	}
+
+	// within the window: both cookies are re-issued, the CSRF token is kept
+	clock.Advance(9 * time.Minute)
+	if rec := post(); rec.Code != http.StatusOK || len(rec.Result().Cookies()) != 2 {
+		t.Fatalf("refresh: got %d with %d cookies", rec.Code, len(rec.Result().Cookies()))
+	}
+	if c := jar[cookieOpts.CSRFCookieName()]; c.Value != csrf || c.MaxAge != 600 {
+		t.Fatalf("the CSRF cookie should be re-issued with the fresh lifetime: %+v", c)
+	}
+
+	// the first token expired, the session and its CSRF cookie didn't
+	clock.Advance(5 * time.Minute)
+	if rec := post(); rec.Code != http.StatusOK {
+		t.Fatalf("after the first exp: got %d, want 200", rec.Code)
+	}
+}
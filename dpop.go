@@ -0,0 +1,403 @@
+package gohelpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultDPoPProofMaxAge is how old the iat of a DPoP proof could be when `DPoPOptions.MaxAge` is not set.
+const DefaultDPoPProofMaxAge = time.Minute
+
+const dpopProofType = "dpop+jwt"
+
+// DPoPOptions config of a `DPoPVerifier`.
+type DPoPOptions struct {
+	AllowedMethods []string      // algorithms of the proofs, default: ES256, ES384, EdDSA, RS256, PS256
+	MaxAge         time.Duration // default: DefaultDPoPProofMaxAge
+	Leeway         time.Duration // clock skew tolerated on the proof iat, default: DefaultLeeway
+	ReplayCache    ReplayCache   // remembers the proof jti, default: a `MemoryReplayCache` of the verifier
+	Clock          Clock         // default: SystemClock
+
+	// RequestURL returns the URL the client called, to match the proof htu. Default: http(s)://Host/path of the request,
+	// set it behind a proxy that rewrites the scheme, host or path.
+	RequestURL func(r *http.Request) string
+
+	// Required rejects the requests without DPoP, otherwise the tokens that are not bound to a key are still accepted
+	// with the Bearer scheme, e.g. while the clients migrate.
+	Required bool
+}
+
+/*
+DPoPVerifier verifies the DPoP proofs (RFC 9449) of the requests: the proof is a JWT signed by the client key, sent in the
+`DPoP` header with `Authorization: DPoP <token>`. A stolen token is useless without the client private key.
+Set it in `ParseOptions.DPoP`, thus `ParseFromRequest` and `Authenticate` check the proof and the token binding:
+
+	dpop := gohelpers.NewDPoPVerifier(gohelpers.DPoPOptions{})
+	auth := gohelpers.Authenticate(gohelpers.AuthOptions{ParseOptions: gohelpers.ParseOptions{Secret: secret, DPoP: dpop}})
+
+Issue the bound tokens with `WithDPoPBinding`. DPoP nonces are not supported. The failures are `ErrTokenBadProof` errors.
+*/
+type DPoPVerifier struct {
+	opts    DPoPOptions
+	methods []string
+	maxAge  time.Duration
+	leeway  time.Duration
+	cache   ReplayCache
+	clock   Clock
+	parser  *jwt.Parser
+}
+
+// NewDPoPVerifier builds a `DPoPVerifier` from the options, share it: its default replay cache is in memory.
+func NewDPoPVerifier(opts DPoPOptions) *DPoPVerifier {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{"ES256", "ES384", "EdDSA", "RS256", "PS256"}
+	}
+	maxAge := opts.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultDPoPProofMaxAge
+	}
+	leeway := opts.Leeway
+	if leeway == 0 {
+		leeway = DefaultLeeway
+	}
+	clock := clockOrSystem(opts.Clock)
+	cache := opts.ReplayCache
+	if cache == nil {
+		cache = NewMemoryReplayCache(clock)
+	}
+
+	return &DPoPVerifier{
+		opts:    opts,
+		methods: methods,
+		maxAge:  maxAge,
+		leeway:  leeway,
+		cache:   cache,
+		clock:   clock,
+		parser:  jwt.NewParser(jwt.WithValidMethods(methods), jwt.WithLeeway(leeway), jwt.WithTimeFunc(clock.Now)),
+	}
+}
+
+// DPoPProof is a verified DPoP proof.
+type DPoPProof struct {
+	JWK        *JWK   // the public key of the client
+	Thumbprint string // RFC 7638 thumbprint of the JWK, the cnf.jkt of the bound tokens
+	ID         string // jti
+	Method     string // htm
+	URL        string // htu
+	IssuedAt   time.Time
+}
+
+type dpopClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+	jwt.RegisteredClaims
+}
+
+/*
+VerifyProof verifies the `DPoP` header of the request: its signature with the embedded JWK, htm, htu, iat, and the jti replay.
+With an access token, the proof ath must be its hash. It doesn't check the token binding, `ParseOptions.DPoP` does.
+*/
+func (d *DPoPVerifier) VerifyProof(r *http.Request, accessToken string) (*DPoPProof, error) {
+	proof, err := d.verifyProof(r, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.recordProof(proof); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// verifyProof is `VerifyProof` without the jti replay, the caller records the proof once all its checks passed.
+func (d *DPoPVerifier) verifyProof(r *http.Request, accessToken string) (*DPoPProof, error) {
+	values := r.Header.Values("DPoP")
+	if len(values) != 1 {
+		return nil, badProof("expected one DPoP header, got %d", len(values))
+	}
+
+	var claims dpopClaims
+	token, err := d.parser.ParseWithClaims(values[0], &claims, d.proofKey)
+	if err != nil {
+		return nil, &TokenError{Kind: TokenBadProof, Err: err}
+	}
+	jwk, _ := headerJWK(token.Header)
+
+	if claims.HTM != r.Method {
+		return nil, badProof("htm %q doesn't match the request method %s", claims.HTM, r.Method)
+	}
+	htu, errProof := normalizeHTU(claims.HTU)
+	requestURL, errRequest := normalizeHTU(d.requestURL(r))
+	if errProof != nil || errRequest != nil || htu != requestURL {
+		return nil, badProof("htu %q doesn't match the request URL", claims.HTU)
+	}
+
+	if claims.IssuedAt == nil {
+		return nil, badProof(`claim "iat" is required`)
+	}
+	now := d.clock.Now()
+	if age := now.Sub(claims.IssuedAt.Time); age > d.maxAge+d.leeway || age < -d.leeway {
+		return nil, badProof("proof issued at %s is out of the accepted window", claims.IssuedAt.UTC().Format(time.RFC3339))
+	}
+	if claims.ID == "" {
+		return nil, badProof(`claim "jti" is required`)
+	}
+	if accessToken != "" && claims.ATH != accessTokenHash(accessToken) {
+		return nil, badProof("ath doesn't match the access token")
+	}
+
+	thumbprint, err := jwk.Thumbprint()
+	if err != nil {
+		return nil, &TokenError{Kind: TokenBadProof, Err: err}
+	}
+
+	return &DPoPProof{
+		JWK:        jwk,
+		Thumbprint: thumbprint,
+		ID:         claims.ID,
+		Method:     claims.HTM,
+		URL:        claims.HTU,
+		IssuedAt:   claims.IssuedAt.Time,
+	}, nil
+}
+
+// recordProof records the proof jti in the replay cache, a proof already recorded is a replay.
+func (d *DPoPVerifier) recordProof(proof *DPoPProof) error {
+	seen, err := d.cache.Seen("dpop:"+proof.ID, proof.IssuedAt.Add(d.maxAge+d.leeway))
+	if err != nil {
+		return &TokenError{Kind: TokenMisconfigured, Err: fmt.Errorf("replay cache: %w", err)}
+	}
+	if seen {
+		return badProof("proof %q was already used", proof.ID)
+	}
+	return nil
+}
+
+// checkRequest checks the proof of a verified token: a token bound to a key (cnf.jkt) requires the DPoP scheme and a proof of that key.
+func (d *DPoPVerifier) checkRequest(r *http.Request, accessToken string, token *jwt.Token) error {
+	jkt := confirmationThumbprint(token)
+
+	scheme, _, _ := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if !strings.EqualFold(scheme, "DPoP") {
+		switch {
+		case jkt != "":
+			return badProof("the token is bound to a DPoP key, it requires the DPoP scheme and a proof")
+		case d.opts.Required:
+			return badProof("DPoP is required")
+		}
+		return nil
+	}
+
+	if jkt == "" {
+		return badProof("the token is not bound to a DPoP key")
+	}
+	proof, err := d.verifyProof(r, accessToken)
+	if err != nil {
+		return err
+	}
+	if proof.Thumbprint != jkt {
+		return badProof("the proof key doesn't match the token cnf.jkt")
+	}
+
+	// recorded last, a proof rejected by the checks above doesn't burn its jti
+	return d.recordProof(proof)
+}
+
+func (d *DPoPVerifier) proofKey(token *jwt.Token) (interface{}, error) {
+	if typ, _ := token.Header["typ"].(string); typ != dpopProofType {
+		return nil, fmt.Errorf("typ must be %q", dpopProofType)
+	}
+	jwk, err := headerJWK(token.Header)
+	if err != nil {
+		return nil, err
+	}
+	return jwk.PublicKey()
+}
+
+func (d *DPoPVerifier) requestURL(r *http.Request) string {
+	if d.opts.RequestURL != nil {
+		return d.opts.RequestURL(r)
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.EscapedPath()
+}
+
+// headerJWK decodes the public "jwk" header of a proof.
+func headerJWK(header map[string]interface{}) (*JWK, error) {
+	raw, ok := header["jwk"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`missing "jwk" header`)
+	}
+	if _, private := raw["d"]; private {
+		return nil, errors.New(`the "jwk" header must not carry a private key`)
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+	return &jwk, nil
+}
+
+// confirmationThumbprint returns the cnf.jkt claim of a token, empty when the token is not bound to a key.
+func confirmationThumbprint(token *jwt.Token) string {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	cnf, _ := claims["cnf"].(map[string]interface{})
+	jkt, _ := cnf["jkt"].(string)
+	return jkt
+}
+
+// normalizeHTU returns the URL without query and fragment, with the scheme and host lowercased and the default port removed (RFC 9449, section 4.3).
+func normalizeHTU(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%q is not an absolute URL", raw)
+	}
+
+	scheme, host, port := strings.ToLower(u.Scheme), strings.ToLower(u.Hostname()), u.Port()
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return scheme + "://" + host + path, nil
+}
+
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func badProof(format string, args ...interface{}) *TokenError {
+	return &TokenError{Kind: TokenBadProof, Err: fmt.Errorf(format, args...)}
+}
+
+// WithDPoPBinding binds the token to a client key, it sets the cnf.jkt claim to the key thumbprint, e.g. `DPoPProof.Thumbprint`.
+func WithDPoPBinding(jkt string) TokenOption {
+	return WithClaim("cnf", map[string]interface{}{"jkt": jkt})
+}
+
+/*
+DPoPSigner creates the DPoP proofs of a client key, e.g. in a Go client or the tests of a service:
+
+	key, _, _ := gohelpers.GenerateSigningKey(gohelpers.KeyTypeECDSA)
+	signer, err := gohelpers.NewDPoPSigner(key)
+	// send signer.Thumbprint() when asking for a token, then on each request:
+	err = signer.SignRequest(req, accessToken)
+*/
+type DPoPSigner struct {
+	key        crypto.Signer
+	method     jwt.SigningMethod
+	jwk        *JWK
+	thumbprint string
+	clock      Clock
+}
+
+// NewDPoPSigner returns the signer of an *ecdsa.PrivateKey (P-256 or P-384), ed25519.PrivateKey or *rsa.PrivateKey.
+func NewDPoPSigner(key crypto.Signer, clock ...Clock) (*DPoPSigner, error) {
+	var method jwt.SigningMethod
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		method = jwt.SigningMethodES256
+		if k.Curve.Params().Name == "P-384" {
+			method = jwt.SigningMethodES384
+		}
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	jwk, err := NewJWK(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := jwk.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	var c Clock
+	if len(clock) > 0 {
+		c = clock[0]
+	}
+	return &DPoPSigner{key: key, method: method, jwk: jwk, thumbprint: thumbprint, clock: clockOrSystem(c)}, nil
+}
+
+// Thumbprint returns the thumbprint of the client key, the cnf.jkt of its tokens.
+func (s *DPoPSigner) Thumbprint() string {
+	return s.thumbprint
+}
+
+// Proof returns a proof of the request method and URL (the query and fragment are dropped). The access token is optional, it sets ath.
+func (s *DPoPSigner) Proof(method, requestURL, accessToken string) (string, error) {
+	htu, err := url.Parse(requestURL)
+	if err != nil {
+		return "", err
+	}
+	htu.RawQuery, htu.Fragment = "", ""
+
+	claims := jwt.MapClaims{
+		"jti": newJTI(),
+		"htm": method,
+		"htu": htu.String(),
+		"iat": s.clock.Now().Unix(),
+	}
+	if accessToken != "" {
+		claims["ath"] = accessTokenHash(accessToken)
+	}
+
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["typ"] = dpopProofType
+	token.Header["jwk"] = s.jwk
+	return token.SignedString(s.key)
+}
+
+// SignRequest sets the `Authorization: DPoP <token>` and `DPoP` headers of a client request, its URL must be absolute.
+func (s *DPoPSigner) SignRequest(r *http.Request, accessToken string) error {
+	proof, err := s.Proof(r.Method, r.URL.String(), accessToken)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "DPoP "+accessToken)
+	r.Header.Set("DPoP", proof)
+	return nil
+}
@@ -0,0 +1,157 @@
+package gohelpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDPoP_FromRequest(t *testing.T) {
+	secret := []byte("s3cr3t")
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	clientKey, _, _ := GenerateSigningKey(KeyTypeECDSA)
+	client, err := NewDPoPSigner(clientKey, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, _ := GenerateSigningKey(KeyTypeEd25519)
+	other, _ := NewDPoPSigner(otherKey, clock)
+
+	opts := ParseOptions{Secret: secret, Clock: clock, DPoP: NewDPoPVerifier(DPoPOptions{Clock: clock})}
+	bound, _ := NewToken(WithSecret(secret), WithClock(clock), WithDPoPBinding(client.Thumbprint()))
+	unbound, _ := NewToken(WithSecret(secret), WithClock(clock))
+
+	newRequest := func(method, target string) *http.Request {
+		return httptest.NewRequest(method, target, nil)
+	}
+
+	// a valid proof, then its replay
+	req := newRequest(http.MethodGet, "http://api.local/orders?page=2")
+	if err := client.SignRequest(req, bound); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseFromRequest(req, opts); err != nil {
+		t.Fatalf("valid proof: %v", err)
+	}
+	if _, err := ParseFromRequest(req, opts); !errors.Is(err, ErrTokenBadProof) || !strings.Contains(err.Error(), "already used") {
+		t.Fatalf("replayed proof: expected ErrTokenBadProof, got %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		prepare func() *http.Request
+	}{
+		{"bound token as bearer", func() *http.Request {
+			r := newRequest(http.MethodGet, "http://api.local/orders")
+			r.Header.Set("Authorization", "Bearer "+bound)
+			return r
+		}},
+		{"missing proof", func() *http.Request {
+			r := newRequest(http.MethodGet, "http://api.local/orders")
+			r.Header.Set("Authorization", "DPoP "+bound)
+			return r
+		}},
+		{"other client key", func() *http.Request {
+			r := newRequest(http.MethodGet, "http://api.local/orders")
+			other.SignRequest(r, bound)
+			return r
+		}},
+		{"unbound token with DPoP", func() *http.Request {
+			r := newRequest(http.MethodGet, "http://api.local/orders")
+			client.SignRequest(r, unbound)
+			return r
+		}},
+		{"other method", func() *http.Request {
+			r := newRequest(http.MethodPost, "http://api.local/orders")
+			proof, _ := client.Proof(http.MethodGet, "http://api.local/orders", bound)
+			r.Header.Set("Authorization", "DPoP "+bound)
+			r.Header.Set("DPoP", proof)
+			return r
+		}},
+		{"other URL", func() *http.Request {
+			r := newRequest(http.MethodGet, "http://api.local/orders")
+			proof, _ := client.Proof(http.MethodGet, "http://api.local/admin", bound)
+			r.Header.Set("Authorization", "DPoP "+bound)
+			r.Header.Set("DPoP", proof)
+			return r
+		}},
+		{"other access token", func() *http.Request {
+			r := newRequest(http.MethodGet, "http://api.local/orders")
+			proof, _ := client.Proof(http.MethodGet, "http://api.local/orders", unbound)
+			r.Header.Set("Authorization", "DPoP "+bound)
+			r.Header.Set("DPoP", proof)
+			return r
+		}},
+		{"old proof", func() *http.Request {
+			r := newRequest(http.MethodGet, "http://api.local/orders")
+			old, _ := NewDPoPSigner(clientKey, NewFakeClock(clock.Now().Add(-5*time.Minute)))
+			old.SignRequest(r, bound)
+			return r
+		}},
+	}
+	for _, c := range cases {
+		if _, err := ParseFromRequest(c.prepare(), opts); !errors.Is(err, ErrTokenBadProof) {
+			t.Errorf("%s: expected ErrTokenBadProof, got %v", c.name, err)
+		}
+	}
+
+	// unbound bearer tokens are accepted, unless DPoP is required
+	req = newRequest(http.MethodGet, "http://api.local/orders")
+	req.Header.Set("Authorization", "Bearer "+unbound)
+	if _, err := ParseFromRequest(req, opts); err != nil {
+		t.Fatalf("unbound bearer token: %v", err)
+	}
+	opts.DPoP = NewDPoPVerifier(DPoPOptions{Clock: clock, Required: true})
+	if _, err := ParseFromRequest(req, opts); !errors.Is(err, ErrTokenBadProof) {
+		t.Fatalf("required DPoP: expected ErrTokenBadProof, got %v", err)
+	}
+}
+
+func TestDPoP_HTUNormalization(t *testing.T) {
+	verifier := NewDPoPVerifier(DPoPOptions{})
+	key, _, _ := GenerateSigningKey(KeyTypeEd25519)
+	client, _ := NewDPoPSigner(key)
+
+	proof, _ := client.Proof(http.MethodGet, "HTTPS://API.local:443/orders#top", "")
+	req := httptest.NewRequest(http.MethodGet, "https://api.local/orders?page=2", nil)
+	req.Header.Set("DPoP", proof)
+	if _, err := verifier.VerifyProof(req, ""); err != nil {
+		t.Fatalf("normalized htu: %v", err)
+	}
+}
+
+func TestDPoP_ErrorResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteBearerError(rec, httptest.NewRequest(http.MethodGet, "/", nil), badProof("missing proof"))
+
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), `DPoP error="invalid_dpop_proof"`) {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestDPoP_MismatchedProofKeepsJTI(t *testing.T) {
+	secret := []byte("s3cr3t")
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	clientKey, _, _ := GenerateSigningKey(KeyTypeECDSA)
+	client, _ := NewDPoPSigner(clientKey, clock)
+	otherKey, _, _ := GenerateSigningKey(KeyTypeEd25519)
+	other, _ := NewDPoPSigner(otherKey, clock)
+
+	dpop := NewDPoPVerifier(DPoPOptions{Clock: clock})
+	opts := ParseOptions{Secret: secret, Clock: clock, DPoP: dpop}
+	bound, _ := NewToken(WithSecret(secret), WithClock(clock), WithDPoPBinding(other.Thumbprint()))
+
+	req := httptest.NewRequest(http.MethodGet, "http://api.local/orders", nil)
+	client.SignRequest(req, bound)
+	if _, err := ParseFromRequest(req, opts); !errors.Is(err, ErrTokenBadProof) || !strings.Contains(err.Error(), "cnf.jkt") {
+		t.Fatalf("mismatched proof key: expected ErrTokenBadProof, got %v", err)
+	}
+
+	// the rejected proof was not recorded, its jti is still unused
+	if _, err := dpop.VerifyProof(req, bound); err != nil {
+		t.Fatalf("the rejected proof should not burn its jti: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package gohelpers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/*
+TokenExtractor reads the raw token from a request, set it in `ParseOptions.Extractor`.
+It returns an empty token and a nil error when its source doesn't carry a token, thus a chain could try the next one.
+*/
+type TokenExtractor interface {
+	ExtractToken(r *http.Request) (string, TokenSource, error)
+}
+
+// TokenExtractorFunc adapts a func to a `TokenExtractor`.
+type TokenExtractorFunc func(r *http.Request) (string, TokenSource, error)
+
+func (f TokenExtractorFunc) ExtractToken(r *http.Request) (string, TokenSource, error) {
+	return f(r)
+}
+
+// AuthHeaderExtractor reads "Authorization: <scheme> <token>", the scheme is case-insensitive. Default scheme: "Bearer".
+func AuthHeaderExtractor(schemes ...string) TokenExtractor {
+	if len(schemes) == 0 {
+		schemes = []string{"Bearer"}
+	}
+
+	return TokenExtractorFunc(func(r *http.Request) (string, TokenSource, error) {
+		parts := strings.Fields(r.Header.Get("Authorization"))
+		if len(parts) != 2 {
+			return "", "", nil
+		}
+		for _, scheme := range schemes {
+			if strings.EqualFold(parts[0], scheme) {
+				return parts[1], SourceHeader, nil
+			}
+		}
+		return "", "", nil
+	})
+}
+
+// HeaderExtractor reads the raw token from a custom header, e.g. "X-Access-Token".
+func HeaderExtractor(name string) TokenExtractor {
+	return TokenExtractorFunc(func(r *http.Request) (string, TokenSource, error) {
+		return strings.TrimSpace(r.Header.Get(name)), SourceHeader, nil
+	})
+}
+
+// CookieExtractor reads the token from the cookie.
+func CookieExtractor(name string) TokenExtractor {
+	return TokenExtractorFunc(func(r *http.Request) (string, TokenSource, error) {
+		if c, err := r.Cookie(name); err == nil && c != nil {
+			return strings.TrimSpace(c.Value), SourceCookie, nil
+		}
+		return "", "", nil
+	})
+}
+
+// QueryExtractor reads the token from the query param, e.g. ?token=...
+func QueryExtractor(param string) TokenExtractor {
+	return TokenExtractorFunc(func(r *http.Request) (string, TokenSource, error) {
+		return strings.TrimSpace(r.URL.Query().Get(param)), SourceQuery, nil
+	})
+}
+
+// FormExtractor reads the token from a field of an urlencoded or multipart POST body.
+func FormExtractor(field string) TokenExtractor {
+	return TokenExtractorFunc(func(r *http.Request) (string, TokenSource, error) {
+		return strings.TrimSpace(r.PostFormValue(field)), SourceForm, nil
+	})
+}
+
+/*
+WebSocketProtocolExtractor reads the token from the `Sec-WebSocket-Protocol` header, browsers can't set other headers on a WebSocket.
+Both conventions are supported, with marker "access_token":
+  - new WebSocket(url, ["access_token", token]): the token is the protocol after the marker
+  - new WebSocket(url, ["access_token." + token]): the token is the protocol suffix after the marker and a dot
+*/
+func WebSocketProtocolExtractor(marker string) TokenExtractor {
+	return TokenExtractorFunc(func(r *http.Request) (string, TokenSource, error) {
+		var protocols []string
+		for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+			for _, p := range strings.Split(v, ",") {
+				protocols = append(protocols, strings.TrimSpace(p))
+			}
+		}
+
+		for i, p := range protocols {
+			if p == marker && i+1 < len(protocols) {
+				return protocols[i+1], SourceWebSocket, nil
+			}
+			if token, ok := strings.CutPrefix(p, marker+"."); ok {
+				return token, SourceWebSocket, nil
+			}
+		}
+		return "", "", nil
+	})
+}
+
+/*
+ExtractorChain tries the extractors in order and returns the first token found, or no token and no error when none has one,
+thus a chain could be nested in another.
+With RejectConflicts, all of them are tried, and a request that carries different tokens is rejected with `ErrTokenConflict`.
+*/
+type ExtractorChain struct {
+	Extractors      []TokenExtractor
+	RejectConflicts bool
+}
+
+// ChainExtractors returns an `ExtractorChain` of the extractors.
+func ChainExtractors(extractors ...TokenExtractor) *ExtractorChain {
+	return &ExtractorChain{Extractors: extractors}
+}
+
+func (c *ExtractorChain) ExtractToken(r *http.Request) (string, TokenSource, error) {
+	var found string
+	var foundSource TokenSource
+
+	for _, extractor := range c.Extractors {
+		token, source, err := extractor.ExtractToken(r)
+		if err != nil {
+			return "", source, err
+		}
+		if token == "" {
+			continue
+		}
+		if !c.RejectConflicts {
+			return token, source, nil
+		}
+		if found != "" && token != found {
+			return "", source, &TokenError{
+				Kind:   TokenConflict,
+				Source: source,
+				Err:    fmt.Errorf("the %s token differs from the %s token", source, foundSource),
+			}
+		}
+		if found == "" {
+			found, foundSource = token, source
+		}
+	}
+
+	return found, foundSource, nil
+}
+
+// defaultExtractor is the chain of the `ParseOptions` knobs: Authorization header (also the DPoP scheme with `ParseOptions.DPoP`), then cookie, then query.
+func defaultExtractor(opts ParseOptions) TokenExtractor {
+	schemes := []string{"Bearer"}
+	if opts.DPoP != nil {
+		schemes = append(schemes, "DPoP")
+	}
+	chain := ChainExtractors(AuthHeaderExtractor(schemes...))
+	if opts.CookieName != "" {
+		chain.Extractors = append(chain.Extractors, CookieExtractor(opts.CookieName))
+	}
+	if opts.QueryParam != "" {
+		chain.Extractors = append(chain.Extractors, QueryExtractor(opts.QueryParam))
+	}
+	return chain
+}
@@ -0,0 +1,115 @@
+package gohelpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTokenExtractors(t *testing.T) {
+	form := url.Values{"access_token": {"form-tok"}}
+
+	cases := []struct {
+		name       string
+		extractor  TokenExtractor
+		prepare    func(r *http.Request)
+		wantToken  string
+		wantSource TokenSource
+	}{
+		{"bearer", AuthHeaderExtractor(), func(r *http.Request) { r.Header.Set("Authorization", "bearer abc") }, "abc", SourceHeader},
+		{"other scheme", AuthHeaderExtractor("Token", "JWT"), func(r *http.Request) { r.Header.Set("Authorization", "JWT abc") }, "abc", SourceHeader},
+		{"wrong scheme", AuthHeaderExtractor(), func(r *http.Request) { r.Header.Set("Authorization", "Basic abc") }, "", ""},
+		{"custom header", HeaderExtractor("X-Access-Token"), func(r *http.Request) { r.Header.Set("X-Access-Token", "abc") }, "abc", SourceHeader},
+		{"form", FormExtractor("access_token"), func(r *http.Request) {
+			r.Method = http.MethodPost
+			r.Body = http.NoBody
+			r.PostForm = form
+		}, "form-tok", SourceForm},
+		{"websocket pair", WebSocketProtocolExtractor("access_token"), func(r *http.Request) {
+			r.Header.Set("Sec-WebSocket-Protocol", "chat, access_token, abc")
+		}, "abc", SourceWebSocket},
+		{"websocket prefix", WebSocketProtocolExtractor("access_token"), func(r *http.Request) {
+			r.Header.Set("Sec-WebSocket-Protocol", "access_token.abc, chat")
+		}, "abc", SourceWebSocket},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+		c.prepare(req)
+		token, source, err := c.extractor.ExtractToken(req)
+		if err != nil || token != c.wantToken || (token != "" && source != c.wantSource) {
+			t.Errorf("%s: got %q %q %v, want %q %q", c.name, token, source, err, c.wantToken, c.wantSource)
+		}
+	}
+}
+
+func TestExtractorChain(t *testing.T) {
+	secret := []byte("s3cr3t")
+	tok, _ := GenerateJwtToken(secret)
+	other, _ := GenerateJwtToken(secret)
+
+	chain := &ExtractorChain{
+		Extractors:      []TokenExtractor{QueryExtractor("token"), HeaderExtractor("X-Access-Token")},
+		RejectConflicts: true,
+	}
+	opts := ParseOptions{Secret: secret, Extractor: chain}
+
+	// custom order and sources
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("X-Access-Token", tok)
+	if _, err := ParseFromRequest(req, opts); err != nil {
+		t.Fatalf("custom header: %v", err)
+	}
+
+	// the Authorization header is not in the chain
+	req = httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	if _, err := ParseFromRequest(req, opts); !errors.Is(err, ErrTokenMissing) {
+		t.Fatalf("expected ErrTokenMissing, got %v", err)
+	}
+
+	// the same token in two sources is fine, different ones are rejected
+	req = httptest.NewRequest(http.MethodGet, "http://x.local/api?token="+tok, nil)
+	req.Header.Set("X-Access-Token", tok)
+	if _, err := ParseFromRequest(req, opts); err != nil {
+		t.Fatalf("same token twice: %v", err)
+	}
+	req.Header.Set("X-Access-Token", other)
+	_, err := ParseFromRequest(req, opts)
+	if !errors.Is(err, ErrTokenConflict) {
+		t.Fatalf("expected ErrTokenConflict, got %v", err)
+	}
+	if be := NewBearerError(err); be.Status != http.StatusBadRequest || be.Code != BearerInvalidRequest {
+		t.Fatalf("conflict should map to 400 invalid_request, got %+v", be)
+	}
+}
+
+func TestExtractorChain_Nested(t *testing.T) {
+	nested := ChainExtractors(QueryExtractor("token"), HeaderExtractor("X-Access-Token"))
+	chain := ChainExtractors(nested, CookieExtractor("access_token"))
+
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	if token, source, err := nested.ExtractToken(req); token != "" || source != "" || err != nil {
+		t.Fatalf("no token should be no error, got %q, %q, %v", token, source, err)
+	}
+
+	// the empty nested chain lets the outer one try the cookie
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "abc"})
+	if token, source, err := chain.ExtractToken(req); token != "abc" || source != SourceCookie || err != nil {
+		t.Fatalf("got %q, %q, %v", token, source, err)
+	}
+}
+
+func TestFormExtractor_Request(t *testing.T) {
+	secret := []byte("s3cr3t")
+	tok, _ := GenerateJwtToken(secret)
+
+	req := httptest.NewRequest(http.MethodPost, "http://x.local/api", strings.NewReader("access_token="+tok))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, err := ParseFromRequest(req, ParseOptions{Secret: secret, Extractor: FormExtractor("access_token")}); err != nil {
+		t.Fatalf("form token: %v", err)
+	}
+}
@@ -5,4 +5,7 @@ go 1.23.0
 require (
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	golang.org/x/crypto v0.41.0
+	golang.org/x/term v0.34.0
 )
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -15,16 +15,23 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Hash password with the bcrypt
+// Hash password with the `DefaultPasswordHasher`, bcrypt with bcrypt.DefaultCost unless it's set to another `PasswordHasher`.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return DefaultPasswordHasher.Hash(password)
+}
+
+// Hash password with the bcrypt of the given cost, between bcrypt.MinCost (4) and bcrypt.MaxCost (31). Each +1 doubles the hashing time.
+func HashPasswordWithCost(password string, cost int) (string, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return "", fmt.Errorf("the bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	return string(bytes), err
 }
 
-// Verify if two passwords matched with bcrypt
+// Verify if two passwords matched, the algorithm (bcrypt, argon2id or scrypt) is detected from the hash. See `CheckHashedPassword` to tell a wrong password from a broken hash.
 func VerifyHashedPassword(plain, hashed string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain))
-	return err == nil
+	return CheckHashedPassword(plain, hashed) == nil
 }
 
 // Convert a struct of any type to a map of keys of strings and values of any type.
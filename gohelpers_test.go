@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestMain(m *testing.M) {
@@ -73,6 +74,33 @@ func TestGetEnvKey(t *testing.T) {
 	}
 }
 
+func TestHashPasswordWithCost(t *testing.T) {
+	hashed, err := HashPasswordWithCost("secret", bcrypt.MinCost)
+	if err != nil || !VerifyHashedPassword("secret", hashed) {
+		t.Fatalf("HashPasswordWithCost = %q, %v", hashed, err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(hashed)); cost != bcrypt.MinCost {
+		t.Fatalf("got cost %d, want %d", cost, bcrypt.MinCost)
+	}
+	if _, err := HashPasswordWithCost("secret", 3); err == nil {
+		t.Fatal("expected an error for a cost under bcrypt.MinCost")
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	data := []byte("# comment\nA=1\nB=x=y\nmalformed\n=no_key\nA=2\n")
+
+	vars := ParseDotEnv(data)
+	if len(vars) != 3 || vars["A"] != "2" || vars["B"] != "x=y" || vars[""] != "no_key" {
+		t.Fatalf("ParseDotEnv = %v", vars)
+	}
+
+	lines := ParseDotEnvLines(data)
+	if len(lines) != 7 || !lines[0].Skip || lines[2].Key != "B" || !lines[3].Malformed() || lines[4].Malformed() || lines[4].Value != "no_key" || lines[5].Number != 6 {
+		t.Fatalf("ParseDotEnvLines = %+v", lines)
+	}
+}
+
 func TestHashPassword(t *testing.T) {
 	password := "secret"
 	hashed, err := HashPassword(password)
@@ -178,6 +206,39 @@ func TestVerifyTokenWithExpiredToken(t *testing.T) {
 	}
 }
 
+func TestVerifyTokenWithExpiredToken_FakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	secretKey, _ := GenerateSecretKey("SECRET_KEY")
+	token, _ := GenerateJwtTokenWithClock(secretKey, clock)
+	verifier, _ := NewVerifier(ParseOptions{Secret: secretKey, Clock: clock})
+
+	if isValid, err := verifier.Verify(token); !isValid || err != nil {
+		t.Fatalf("the fresh token should be valid, got %v, %v", isValid, err)
+	}
+
+	// expired beyond the leeway, tokens that expired within it are still accepted
+	clock.Advance(DefaultTokenTTL + DefaultLeeway + time.Second)
+
+	if isValid, err := verifier.Verify(token); isValid || !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected ErrTokenExpired, got %v, %v", isValid, err)
+	}
+}
+
+func TestVerifyTokenWithoutLeeway(t *testing.T) {
+	// expired within the leeway of a Verifier, VerifyJwtToken has none
+	claims := createCustomClaim(time.Now().Add(-2 * time.Second))
+	secretKey, _ := GenerateSecretKey("SECRET_KEY")
+	token, _ := GenerateJwtToken(secretKey, claims)
+
+	if isValid, err := VerifyJwtToken(token, secretKey); isValid || !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected ErrTokenExpired, got %v, %v", isValid, err)
+	}
+	verifier, _ := NewVerifier(ParseOptions{Secret: secretKey})
+	if isValid, err := verifier.Verify(token); !isValid || err != nil {
+		t.Fatalf("the Verifier default leeway should accept it: %v, %v", isValid, err)
+	}
+}
+
 func TestVerifyTokenWithInvalidToken(t *testing.T) {
 	claims := createCustomClaim()
 	secretKey, _ := GenerateSecretKey("SECRET_KEY")
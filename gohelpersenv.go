@@ -28,9 +28,8 @@ func LoadDotEnvToOsEnv(envfile ...string) error {
 		return err
 	}
 
-	parsedData := parseEnvData(data)
+	mapped_data := ParseDotEnv(data)
 	parsedOsEnvData := parseEnvData(os.Environ())
-	mapped_data := SliceStringToMapString(parsedData)
 	currentEnvs := SliceStringToMapString(parsedOsEnvData, "panic")
 
 	for key, val := range mapped_data {
@@ -67,6 +66,43 @@ func GetEnvKey(keyName string) string {
 	return os.Getenv(keyName)
 }
 
+// ParseDotEnv returns the vars of a dotenv file content, exactly as `LoadDotEnvToOsEnv` loads them. On duplicate keys, the last one wins.
+func ParseDotEnv(data []byte) map[string]string {
+	return SliceStringToMapString(parseEnvData(data))
+}
+
+// DotEnvLine is a line of a dotenv file, see `ParseDotEnvLines`.
+type DotEnvLine struct {
+	Number int    // 1-based line number
+	Raw    string // the line as it is in the file
+	Key    string // empty for blank, comment and malformed lines, and for the "=VALUE" lines
+	Value  string
+	Skip   bool // blank or comment line
+}
+
+// Malformed reports whether the line is skipped by the loader because it's not a KEY=VALUE pair.
+func (l DotEnvLine) Malformed() bool {
+	return !l.Skip && !strings.Contains(l.Raw, "=")
+}
+
+// ParseDotEnvLines splits a dotenv file content into lines, with the same rules as `LoadDotEnvToOsEnv`. Useful to lint the file.
+func ParseDotEnvLines(data []byte) []DotEnvLine {
+	rawLines := strings.Split(string(data), "\n")
+	lines := make([]DotEnvLine, 0, len(rawLines))
+
+	for i, raw := range rawLines {
+		line := DotEnvLine{Number: i + 1, Raw: raw}
+		if isSkippedEnvLine(raw) {
+			line.Skip = true
+		} else if key, value, ok := parseEnvLine(raw); ok {
+			line.Key, line.Value = key, value
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
 func parseEnvData(data interface{}) []string {
 	var resultSlice []string
 
@@ -82,18 +118,28 @@ func parseEnvData(data interface{}) []string {
 
 func appendToSlice(in []string, out *[]string) {
 	for _, v := range in {
-		if strings.TrimSpace(v) == "" {
+		if isSkippedEnvLine(v) {
 			continue
 		}
-		if strings.HasPrefix(strings.TrimSpace(v), "#") {
+		key, value, ok := parseEnvLine(v)
+		if !ok {
 			continue
 		}
-		parts := strings.SplitN(v, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		*out = append(*out, parts[0], parts[1])
+		*out = append(*out, key, value)
+	}
+}
+
+func isSkippedEnvLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+func parseEnvLine(line string) (string, string, bool) {
+	parts := strings.SplitN(line, "=", 2)
+	if len(parts) != 2 {
+		return "", "", false
 	}
+	return parts[0], parts[1], true
 }
 
 func defaultEnvFile() string {
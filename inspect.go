@@ -0,0 +1,162 @@
+package gohelpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+TokenInspection is the decoded content of a token, it's UNVERIFIED: the signature and the claims were not checked,
+anyone could have forged them. Use it for debugging only, never to make an auth decision.
+*/
+type TokenInspection struct {
+	Verified          bool                   `json:"verified"` // always false, kept to make it explicit in the output
+	StructurallyValid bool                   `json:"structurally_valid"`
+	Problems          []string               `json:"problems,omitempty"`
+	Header            map[string]interface{} `json:"header"`
+	Claims            jwt.MapClaims          `json:"claims"`
+	Algorithm         string                 `json:"alg"`
+	KeyID             string                 `json:"kid,omitempty"`
+	ExpiresAt         *time.Time             `json:"exp,omitempty"`
+	IssuedAt          *time.Time             `json:"iat,omitempty"`
+	NotBefore         *time.Time             `json:"nbf,omitempty"`
+	Expired           bool                   `json:"expired"`
+	ExpiresIn         time.Duration          `json:"-"` // negative once expired, zero without exp
+}
+
+/*
+InspectToken decodes the header and claims of a token WITHOUT verifying it, to debug a failed `VerifyJwtToken`
+without pasting production tokens into online decoders:
+
+	info, err := gohelpers.InspectToken(token)
+	fmt.Println(info)
+
+The error is a `*TokenError` when the token can't be decoded at all, the inspection is still returned with its Problems.
+*/
+func InspectToken(tokenString string, clock ...Clock) (*TokenInspection, error) {
+	now := SystemClock.Now()
+	if len(clock) > 0 && clock[0] != nil {
+		now = clock[0].Now()
+	}
+
+	info := &TokenInspection{}
+	parts := strings.Split(strings.TrimSpace(tokenString), ".")
+	if len(parts) != 3 {
+		info.Problems = append(info.Problems, fmt.Sprintf("expected 3 segments, got %d", len(parts)))
+		return info, &TokenError{Kind: TokenMalformed, Err: fmt.Errorf("token contains %d segments", len(parts))}
+	}
+
+	parser := jwt.NewParser()
+	if err := decodeSegmentJSON(parser, parts[0], &info.Header); err != nil {
+		info.Problems = append(info.Problems, "header: "+err.Error())
+	}
+	if err := decodeSegmentJSON(parser, parts[1], &info.Claims); err != nil {
+		info.Problems = append(info.Problems, "claims: "+err.Error())
+	}
+	if info.Header == nil || info.Claims == nil {
+		return info, &TokenError{Kind: TokenMalformed, Err: errors.New(strings.Join(info.Problems, ", "))}
+	}
+
+	if sig, err := parser.DecodeSegment(parts[2]); err != nil || len(sig) == 0 {
+		info.Problems = append(info.Problems, "signature: missing or not base64url")
+	}
+
+	info.Algorithm, _ = info.Header["alg"].(string)
+	info.KeyID, _ = info.Header["kid"].(string)
+	switch {
+	case info.Algorithm == "":
+		info.Problems = append(info.Problems, `header: missing "alg"`)
+	case strings.EqualFold(info.Algorithm, "none"):
+		info.Problems = append(info.Problems, `header: "alg" is none, the token is not signed`)
+	case jwt.GetSigningMethod(info.Algorithm) == nil:
+		info.Problems = append(info.Problems, fmt.Sprintf("header: unknown alg %q", info.Algorithm))
+	}
+
+	info.ExpiresAt = inspectTime(info, "exp", info.Claims.GetExpirationTime)
+	info.IssuedAt = inspectTime(info, "iat", info.Claims.GetIssuedAt)
+	info.NotBefore = inspectTime(info, "nbf", info.Claims.GetNotBefore)
+	if info.ExpiresAt != nil {
+		info.ExpiresIn = info.ExpiresAt.Sub(now)
+		info.Expired = info.ExpiresIn <= 0
+	}
+
+	info.StructurallyValid = len(info.Problems) == 0
+
+	return info, nil
+}
+
+// String returns a human readable report of the inspection.
+func (i *TokenInspection) String() string {
+	var b strings.Builder
+
+	b.WriteString("UNVERIFIED token, the signature and claims were not checked\n")
+	fmt.Fprintf(&b, "structurally valid: %v\n", i.StructurallyValid)
+	for _, p := range i.Problems {
+		fmt.Fprintf(&b, "  problem: %s\n", p)
+	}
+	fmt.Fprintf(&b, "alg: %s\n", i.Algorithm)
+	if i.KeyID != "" {
+		fmt.Fprintf(&b, "kid: %s\n", i.KeyID)
+	}
+	for _, t := range []struct {
+		name string
+		at   *time.Time
+	}{{"iat", i.IssuedAt}, {"nbf", i.NotBefore}, {"exp", i.ExpiresAt}} {
+		if t.at != nil {
+			fmt.Fprintf(&b, "%s: %s\n", t.name, t.at.UTC().Format(time.RFC3339))
+		}
+	}
+	switch {
+	case i.ExpiresAt == nil:
+		b.WriteString("expires: never (no exp claim)\n")
+	case i.Expired:
+		fmt.Fprintf(&b, "expired: %s ago\n", (-i.ExpiresIn).Round(time.Second))
+	default:
+		fmt.Fprintf(&b, "expires in: %s\n", i.ExpiresIn.Round(time.Second))
+	}
+
+	keys := make([]string, 0, len(i.Claims))
+	for k := range i.Claims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteString("claims:\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "  %s: %v\n", k, i.Claims[k])
+	}
+
+	return b.String()
+}
+
+func decodeSegmentJSON(parser *jwt.Parser, segment string, dst interface{}) error {
+	raw, err := parser.DecodeSegment(segment)
+	if err != nil {
+		return fmt.Errorf("not base64url: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("not a JSON object: %w", err)
+	}
+	return nil
+}
+
+func inspectTime(info *TokenInspection, name string, get func() (*jwt.NumericDate, error)) *time.Time {
+	date, err := get()
+	if err != nil {
+		info.Problems = append(info.Problems, fmt.Sprintf("claims: %q is not a numeric date", name))
+		return nil
+	}
+	if date == nil {
+		return nil
+	}
+	t := date.Time
+	return &t
+}
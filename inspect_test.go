@@ -0,0 +1,51 @@
+package gohelpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestInspectToken(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	tok, _ := NewToken(WithSecret([]byte("s3cr3t")), WithClock(clock), WithTTL(time.Hour), WithHeader("kid", "k1"), WithSubject("42"))
+
+	clock.Advance(15 * time.Minute)
+	info, err := InspectToken(tok, clock)
+	if err != nil {
+		t.Fatalf("InspectToken error: %v", err)
+	}
+	if info.Verified || !info.StructurallyValid || info.Algorithm != "HS256" || info.KeyID != "k1" {
+		t.Fatalf("unexpected inspection: %+v", info)
+	}
+	if info.Expired || info.ExpiresIn != 45*time.Minute || info.IssuedAt == nil || info.Claims["sub"] != "42" {
+		t.Fatalf("unexpected times or claims: %+v", info)
+	}
+	if report := info.String(); !strings.Contains(report, "UNVERIFIED") || !strings.Contains(report, "expires in: 45m0s") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+
+	clock.Advance(time.Hour)
+	if info, _ := InspectToken(tok, clock); !info.Expired {
+		t.Fatal("expected the token to be reported as expired")
+	}
+}
+
+func TestInspectToken_Problems(t *testing.T) {
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"exp": "tomorrow"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	info, err := InspectToken(unsigned)
+	if err != nil {
+		t.Fatalf("InspectToken error: %v", err)
+	}
+	if info.StructurallyValid || len(info.Problems) != 3 {
+		t.Fatalf("expected alg none, bad exp and missing signature problems, got %v", info.Problems)
+	}
+
+	if _, err := InspectToken("not-a-token"); !errors.Is(err, ErrTokenMalformed) {
+		t.Fatalf("expected ErrTokenMalformed, got %v", err)
+	}
+}
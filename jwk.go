@@ -0,0 +1,113 @@
+package gohelpers
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// JWK is a public JSON Web Key (RFC 7517) of an EC (P-256, P-384), OKP (Ed25519) or RSA key, e.g. the "jwk" header of a DPoP proof.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// NewJWK returns the JWK of a public key, *ecdsa.PublicKey, ed25519.PublicKey or *rsa.PublicKey.
+func NewJWK(key crypto.PublicKey) (*JWK, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		crv := key.Curve.Params().Name
+		if crv != "P-256" && crv != "P-384" {
+			return nil, fmt.Errorf("unsupported curve %s", crv)
+		}
+		return &JWK{Kty: "EC", Crv: crv, X: encode(key.X.FillBytes(make([]byte, size))), Y: encode(key.Y.FillBytes(make([]byte, size)))}, nil
+	case ed25519.PublicKey:
+		return &JWK{Kty: "OKP", Crv: "Ed25519", X: encode(key)}, nil
+	case *rsa.PublicKey:
+		return &JWK{Kty: "RSA", N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes())}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// PublicKey decodes the key, it fails when the key is invalid, e.g. the EC point is not on the curve.
+func (k *JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "EC":
+		curves := map[string]struct {
+			curve elliptic.Curve
+			ecdh  ecdh.Curve
+		}{"P-256": {elliptic.P256(), ecdh.P256()}, "P-384": {elliptic.P384(), ecdh.P384()}}
+		c, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := decode(k.X)
+		y, errY := decode(k.Y)
+		size := (c.curve.Params().BitSize + 7) / 8
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC coordinates")
+		}
+		// the ecdh parser rejects the points that are not on the curve
+		if _, err := c.ecdh.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: c.curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, errN := decode(k.N)
+		e, errE := decode(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of the key, base64url encoded, e.g. the "jkt" of a DPoP bound token.
+func (k *JWK) Thumbprint() (string, error) {
+	// the required members only, json.Marshal sorts the map keys as RFC 7638 requires
+	members := map[string]string{"kty": k.Kty}
+	switch k.Kty {
+	case "EC":
+		members["crv"], members["x"], members["y"] = k.Crv, k.X, k.Y
+	case "OKP":
+		members["crv"], members["x"] = k.Crv, k.X
+	case "RSA":
+		members["e"], members["n"] = k.E, k.N
+	default:
+		return "", fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,55 @@
+package gohelpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// the example key of RFC 7638, section 3.1
+	jwk := &JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	if thumbprint, err := jwk.Thumbprint(); err != nil || thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("Thumbprint() = %q, %v", thumbprint, err)
+	}
+}
+
+func TestJWK_RoundTrip(t *testing.T) {
+	for _, keyType := range []string{KeyTypeEd25519, KeyTypeECDSA, KeyTypeRSA} {
+		key, _, _ := GenerateSigningKey(keyType)
+		jwk, err := NewJWK(key.Public())
+		if err != nil {
+			t.Fatalf("%s: NewJWK: %v", keyType, err)
+		}
+		public, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("%s: PublicKey: %v", keyType, err)
+		}
+
+		var equal bool
+		switch want := key.Public().(type) {
+		case *ecdsa.PublicKey:
+			equal = want.Equal(public)
+		case ed25519.PublicKey:
+			equal = want.Equal(public)
+		case *rsa.PublicKey:
+			equal = want.Equal(public)
+		}
+		if !equal {
+			t.Fatalf("%s: the decoded key differs", keyType)
+		}
+	}
+
+	// the point is not on the curve
+	key, _, _ := GenerateSigningKey(KeyTypeECDSA)
+	jwk, _ := NewJWK(key.Public())
+	jwk.X, jwk.Y = jwk.Y, jwk.X
+	if _, err := jwk.PublicKey(); err == nil {
+		t.Fatal("expected an error for an EC point off the curve")
+	}
+}
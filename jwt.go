@@ -1,12 +1,12 @@
 package gohelpers
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,25 +14,51 @@ import (
 
 // ParseOptions config for parsing/verifying tokens from *http.Request.
 type ParseOptions struct {
-	Secret         []byte        // required
+	Secret         []byte        // required, unless Key or KeyResolver is set
+	Key            interface{}   // verification key of asymmetric methods (e.g. *rsa.PublicKey), used when Secret is empty
+	KeyResolver    KeyResolver   // picks the key and rules per token, e.g. per tenant with `TenantResolver`, replaces Secret and Key
 	AllowedMethods []string      // default: HS256 only
-	Leeway         time.Duration // default: 30s
+	Leeway         time.Duration // default: DefaultLeeway (30s), NoLeeway disables it
 	Audience       string        // optional: add if you set aud in your tokens
+	Audiences      []string      // optional: more accepted audiences, one match is enough
 	Issuer         string        // optional: add if you set iss in your tokens
+	Issuers        []string      // optional: more accepted issuers
+	Clock          Clock         // default: SystemClock
+
+	// Claims policy, see `ClaimsValidator`
+	RequiredClaims []string          // claims that must be present, e.g. "sub", "iat", "tenant_id"
+	RequireExp     bool              // reject tokens without exp, otherwise they never expire
+	MaxAge         time.Duration     // reject tokens issued (iat) longer ago than MaxAge, iat becomes required
+	Validators     []ClaimsValidator // custom checks of the claim values, run after the other checks
+
+	// Revoked is optional, return true to reject a valid token, e.g. its jti is in a deny list.
+	Revoked func(token *jwt.Token) bool
+
+	// DPoP verifies the proof of "Authorization: DPoP" requests and the cnf.jkt binding of their tokens, see `NewDPoPVerifier`.
+	// It applies to the tokens read from requests only (`ParseFromRequest`, `Authenticate`...).
+	DPoP *DPoPVerifier
 
 	// Extraction knobs (header is always tried first)
 	CookieName string // if set, try cookie by this name
 	QueryParam string // if set, try ?token=... (or any custom name)
+	// Extractor replaces the knobs above with a custom order or sources, see `ExtractorChain`.
+	Extractor TokenExtractor
 }
 
 /*
 Generate JWT token, this func will generate an access token or a refresh token, based on the claims.
-If no custom claims sent as second arg, it will go with the `jwtCustomClaims` struct that contains:
-`username`, `uuid`, and `jwt.RegisteredClaims`
+If no custom claims sent as second arg, it will go with `jwt.RegisteredClaims` that expires after `DefaultTokenTTL`.
+To build the claims with options (sub, iss, aud, ttl...), use `NewToken` or a `TokenIssuer`.
 */
 func GenerateJwtToken(secretKey []byte, customClaims ...jwt.Claims) (string, error) {
-	claims := prepareClaims(customClaims)
-	claims = ensureUniqueClaims(claims)
+	return GenerateJwtTokenWithClock(secretKey, SystemClock, customClaims...)
+}
+
+// GenerateJwtTokenWithClock is `GenerateJwtToken` with the clock of the default exp and the iat, e.g. a `FakeClock` in tests.
+func GenerateJwtTokenWithClock(secretKey []byte, clock Clock, customClaims ...jwt.Claims) (string, error) {
+	now := clockOrSystem(clock).Now()
+	claims := prepareClaims(customClaims, now)
+	claims = ensureUniqueClaims(claims, now)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(secretKey)
 
@@ -45,54 +71,27 @@ func GenerateJwtToken(secretKey []byte, customClaims ...jwt.Claims) (string, err
 
 /*
 Verify the issued tokens, access and refresh. You can use the return error and check if the `access_token` is expired. Therefore, generate new one based on the refresh token validity.
-Intended to be used in middlewares.
+Intended to be used in middlewares. The error is a `*TokenError`, match it with `errors.Is(err, ErrTokenExpired)`.
+Unlike the `Verifier`, there's no leeway: the tokens expire at their exact exp, as they always did here.
 */
 func VerifyJwtToken(tokenString string, secretKey []byte) (bool, error) {
-	token, err := jwt.Parse(tokenString, func(jwtToken *jwt.Token) (interface{}, error) {
-		if jwtToken.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, errors.New("unexpected signing method")
-		}
-		return secretKey, nil
-	},
-		// jwt.WithLeeway(30*time.Second),
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
-	)
+	verifier, err := NewVerifier(ParseOptions{Secret: secretKey, Leeway: NoLeeway})
 	if err != nil {
-		switch {
-		case errors.Is(err, jwt.ErrTokenSignatureInvalid):
-			return false, jwt.ErrTokenSignatureInvalid
-		case errors.Is(err, jwt.ErrTokenExpired):
-			return false, jwt.ErrTokenExpired
-		case errors.Is(err, jwt.ErrTokenNotValidYet):
-			return false, jwt.ErrTokenNotValidYet
-		default:
-			return false, err
-		}
-	}
-	if !token.Valid {
-		return false, errors.New("invalid token")
+		return false, err
 	}
-	return true, nil
+	return verifier.Verify(tokenString)
 }
 
 // Get claims from the token, and the used secret key to generate the token.
 func GetClaims(tokenString string, secretKey []byte) (interface{}, error) {
-	token, err := jwt.Parse(tokenString, func(jwtToken *jwt.Token) (interface{}, error) {
-		if jwtToken.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, errors.New("unexpected signing method")
-		}
-		return secretKey, nil
-	}, jwt.WithLeeway(30*time.Second), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	verifier, err := NewVerifier(ParseOptions{Secret: secretKey})
 	if err != nil {
 		return nil, err
 	}
-	if !token.Valid {
-		return nil, errors.New("invalid token")
-	}
-	return token.Claims, nil
+	return verifier.Claims(tokenString)
 }
 
-// GetClaimsAs parses the token and unmarshals claims into dst (pointer to struct).
+// GetClaimsAs parses the token and unmarshals claims into dst (pointer to struct). `ParseClaims` does it without the JSON round trip.
 func GetClaimsAs(tokenString string, secretKey []byte, dst interface{}) error {
 	claims, err := GetClaims(tokenString, secretKey)
 	if err != nil {
@@ -102,13 +101,13 @@ func GetClaimsAs(tokenString string, secretKey []byte, dst interface{}) error {
 	return CastJwtClaimsToCustomClaims(claims, dst)
 }
 
-func prepareClaims(customClaims []jwt.Claims) jwt.Claims {
+func prepareClaims(customClaims []jwt.Claims, now time.Time) jwt.Claims {
 	if len(customClaims) > 0 {
 		return customClaims[0]
 	}
 
 	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(12 * time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(DefaultTokenTTL)),
 	}
 
 	return claims
@@ -133,58 +132,13 @@ func CastJwtClaimsToCustomClaims(mapClaims, claims interface{}) error {
 
 // ParseFromRequest extracts a JWT from the request (Authorization: Bearer ...,
 // or cookie, or query param) and verifies it with the provided options.
-// Works with net/http directly (Gin: use c.Request).
+// Works with net/http directly (Gin: use c.Request). To avoid rebuilding the options on every call, use `NewVerifier` once.
 func ParseFromRequest(r *http.Request, opts ParseOptions) (*jwt.Token, error) {
-	if len(opts.Secret) == 0 {
-		return nil, errors.New("missing secret")
-	}
-	methods := opts.AllowedMethods
-	if len(methods) == 0 {
-		methods = []string{jwt.SigningMethodHS256.Alg()}
-	}
-	leeway := opts.Leeway
-	if leeway == 0 {
-		leeway = 30 * time.Second
-	}
-
-	raw, err := extractTokenString(r, opts)
+	verifier, err := NewVerifier(opts)
 	if err != nil {
 		return nil, err
 	}
-
-	keyFunc := func(t *jwt.Token) (interface{}, error) {
-		if t.Method == nil || t.Method.Alg() == "" {
-			return nil, errors.New("missing signing method")
-		}
-		return opts.Secret, nil
-	}
-
-	var parseOpts []jwt.ParserOption
-	parseOpts = append(parseOpts, jwt.WithValidMethods(methods), jwt.WithLeeway(leeway))
-	if opts.Audience != "" {
-		parseOpts = append(parseOpts, jwt.WithAudience(opts.Audience))
-	}
-	if opts.Issuer != "" {
-		parseOpts = append(parseOpts, jwt.WithIssuer(opts.Issuer))
-	}
-
-	token, parseErr := jwt.Parse(raw, keyFunc, parseOpts...)
-	if parseErr != nil {
-		switch {
-		case errors.Is(parseErr, jwt.ErrTokenSignatureInvalid):
-			return nil, jwt.ErrTokenSignatureInvalid
-		case errors.Is(parseErr, jwt.ErrTokenExpired):
-			return nil, jwt.ErrTokenExpired
-		case errors.Is(parseErr, jwt.ErrTokenNotValidYet):
-			return nil, jwt.ErrTokenNotValidYet
-		default:
-			return nil, parseErr
-		}
-	}
-	if !token.Valid {
-		return nil, errors.New("invalid token")
-	}
-	return token, nil
+	return verifier.FromRequest(r)
 }
 
 // VerifyFromRequest returns (true,nil) when the token from the request is valid.
@@ -220,8 +174,8 @@ func newJTI() string {
 	return hex.EncodeToString(b)
 }
 
-func ensureUniqueClaims(claims jwt.Claims) jwt.Claims {
-	now := time.Now().UTC()
+func ensureUniqueClaims(claims jwt.Claims, now time.Time) jwt.Claims {
+	now = now.UTC()
 
 	switch c := claims.(type) {
 	case jwt.MapClaims:
@@ -245,7 +199,10 @@ func ensureUniqueClaims(claims jwt.Claims) jwt.Claims {
 	default:
 		m := jwt.MapClaims{}
 		if b, err := json.Marshal(c); err == nil {
-			_ = json.Unmarshal(b, &m)
+			// json.Number keeps the precision of large integers
+			decoder := json.NewDecoder(bytes.NewReader(b))
+			decoder.UseNumber()
+			_ = decoder.Decode(&m)
 		}
 
 		if _, ok := m["iat"]; !ok {
@@ -260,39 +217,13 @@ func ensureUniqueClaims(claims jwt.Claims) jwt.Claims {
 	}
 }
 
-func extractTokenString(r *http.Request, opts ParseOptions) (string, error) {
-	// 1) Authorization header
-	if h := r.Header.Get("Authorization"); h != "" {
-		if token := parseBearerHeader(h); token != "" {
-			return token, nil
-		}
-	}
-
-	// 2) Cookie
-	if opts.CookieName != "" {
-		if c, err := r.Cookie(opts.CookieName); err == nil && c != nil && strings.TrimSpace(c.Value) != "" {
-			return c.Value, nil
-		}
-	}
-
-	// 3) Query param
-	if opts.QueryParam != "" {
-		if v := strings.TrimSpace(r.URL.Query().Get(opts.QueryParam)); v != "" {
-			return v, nil
-		}
-	}
-
-	return "", errors.New("token not found in request")
-}
-
-func parseBearerHeader(headerVal string) string {
-	// Accepts case-insensitive "Bearer <token>"
-	parts := strings.Fields(headerVal)
-	if len(parts) != 2 {
-		return ""
+func extractTokenString(r *http.Request, extractor TokenExtractor) (string, TokenSource, error) {
+	token, source, err := extractor.ExtractToken(r)
+	if err != nil {
+		return "", source, newTokenError(err, source)
 	}
-	if strings.ToLower(parts[0]) != "bearer" {
-		return ""
+	if token == "" {
+		return "", "", &TokenError{Kind: TokenMissing, Err: errors.New("token not found in request")}
 	}
-	return strings.TrimSpace(parts[1])
+	return token, source, nil
 }
@@ -0,0 +1,133 @@
+package gohelpers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+KeyResolver picks the verification key and rules of a token, set it in `ParseOptions.KeyResolver` to verify tokens of several
+tenants with one `Verifier`. The header and claims are UNVERIFIED, use them only to choose the key: the signature is checked
+with the resolved key right after.
+
+A returned `*TokenError` keeps its kind, other errors reject the token with `ErrTokenInvalid`.
+*/
+type KeyResolver interface {
+	ResolveKey(header map[string]interface{}, claims jwt.MapClaims) (*ResolvedKey, error)
+}
+
+// KeyResolverFunc is an adapter to use a func as `KeyResolver`.
+type KeyResolverFunc func(header map[string]interface{}, claims jwt.MapClaims) (*ResolvedKey, error)
+
+// ResolveKey calls f(header, claims).
+func (f KeyResolverFunc) ResolveKey(header map[string]interface{}, claims jwt.MapClaims) (*ResolvedKey, error) {
+	return f(header, claims)
+}
+
+// ResolvedKey is the key of a token and the rules of its tenant, the zero rules fall back to the `ParseOptions` ones.
+type ResolvedKey struct {
+	Key            interface{}   // required: the secret ([]byte) or the public key
+	AllowedMethods []string      // default: ParseOptions.AllowedMethods
+	Audiences      []string      // default: ParseOptions.Audience(s)
+	Leeway         time.Duration // default: ParseOptions.Leeway, NoLeeway disables it
+}
+
+// Tenant is the keys and rules of an issuer in a `TenantResolver`.
+type Tenant struct {
+	ResolvedKey                        // Key verifies the tokens without kid header
+	Keys        map[string]interface{} // optional: keys by kid header, e.g. to rotate the secret
+}
+
+/*
+TenantResolver is a `KeyResolver` of the tenants by their iss claim, then by the kid header:
+
+	verifier, err := gohelpers.NewVerifier(gohelpers.ParseOptions{
+		KeyResolver: gohelpers.TenantResolver{
+			"https://acme.example": {ResolvedKey: gohelpers.ResolvedKey{Key: acmeSecret, Audiences: []string{"acme-api"}}},
+			"https://globex.example": {
+				ResolvedKey: gohelpers.ResolvedKey{AllowedMethods: []string{"EdDSA"}, Leeway: time.Minute},
+				Keys:        map[string]interface{}{"2024-01": globexPublicKey},
+			},
+		},
+	})
+
+Tokens of an unknown issuer are rejected with `ErrTokenBadIssuer`, the ones of an unknown kid with `ErrTokenUnknownKey`.
+*/
+type TenantResolver map[string]*Tenant
+
+// ResolveKey returns the key of the token issuer and kid.
+func (t TenantResolver) ResolveKey(header map[string]interface{}, claims jwt.MapClaims) (*ResolvedKey, error) {
+	iss, _ := claims.GetIssuer()
+	tenant, ok := t[iss]
+	if !ok || tenant == nil {
+		return nil, &TokenError{Kind: TokenBadIssuer, Err: fmt.Errorf("unknown issuer %q", iss)}
+	}
+
+	resolved := tenant.ResolvedKey
+	if kid, ok := header["kid"].(string); ok && kid != "" {
+		key, ok := tenant.Keys[kid]
+		if !ok {
+			return nil, &TokenError{Kind: TokenUnknownKey, Err: fmt.Errorf("unknown kid %q of issuer %q", kid, iss)}
+		}
+		resolved.Key = key
+	}
+
+	return &resolved, nil
+}
+
+// resolve returns the parser and key func of the token tenant, its rules fall back to the verifier ones.
+func (v *Verifier) resolve(tokenString string) (*jwt.Parser, jwt.Keyfunc, time.Duration, error) {
+	unverified, _, err := v.parser.ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	resolved, err := v.opts.KeyResolver.ResolveKey(unverified.Header, unverified.Claims.(jwt.MapClaims))
+	if err != nil {
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) {
+			err = &TokenError{Kind: TokenInvalid, Err: err}
+		}
+		return nil, nil, 0, err
+	}
+	if resolved == nil || resolved.Key == nil {
+		return nil, nil, 0, &TokenError{Kind: TokenInvalid, Err: errors.New("no key resolved for the token")}
+	}
+
+	methods := resolved.AllowedMethods
+	if len(methods) == 0 {
+		methods = v.methods
+	}
+	leeway := resolved.Leeway
+	if leeway == 0 {
+		leeway = v.leeway
+	}
+	leeway = max(leeway, 0)
+	audiences := resolved.Audiences
+	if len(audiences) == 0 {
+		audiences = appendNonEmpty(v.opts.Audiences, v.opts.Audience)
+	}
+
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if err := checkMethod(token, methods); err != nil {
+			return nil, err
+		}
+		return resolved.Key, nil
+	}
+
+	return v.parserFor(leeway, audiences), keyFunc, leeway, nil
+}
+
+// parserFor returns the cached parser of the tenant rules, the tenants share it when their rules are the same.
+func (v *Verifier) parserFor(leeway time.Duration, audiences []string) *jwt.Parser {
+	key := fmt.Sprint(leeway) + "|" + strings.Join(audiences, "\x00")
+	if parser, ok := v.parsers.Load(key); ok {
+		return parser.(*jwt.Parser)
+	}
+	parser, _ := v.parsers.LoadOrStore(key, newParser(v.opts, v.clock, leeway, audiences))
+	return parser.(*jwt.Parser)
+}
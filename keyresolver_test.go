@@ -0,0 +1,114 @@
+package gohelpers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTenantResolver(t *testing.T) {
+	acmeSecret, globexSecret := []byte("acme-s3cr3t"), []byte("globex-s3cr3t")
+	edKey, edMethod, _ := GenerateSigningKey(KeyTypeEd25519)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	// tokens of 1m issued 3m ago, expired past the default leeway
+	past := WithClock(NewFakeClock(clock.Now().Add(-3 * time.Minute)))
+
+	verifier, err := NewVerifier(ParseOptions{
+		Clock: clock,
+		KeyResolver: TenantResolver{
+			"acme": {ResolvedKey: ResolvedKey{Key: acmeSecret, Audiences: []string{"acme-api"}}},
+			"globex": {
+				ResolvedKey: ResolvedKey{Key: globexSecret, Leeway: 5 * time.Minute},
+				Keys:        map[string]interface{}{"ed-1": edKey.Public()},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(opts ...TokenOption) string {
+		token, err := NewToken(append([]TokenOption{WithClock(clock), WithTTL(time.Minute)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	valid := map[string]string{
+		"acme secret":    sign(WithSecret(acmeSecret), WithIssuer("acme"), WithAudience("acme-api")),
+		"globex secret":  sign(WithSecret(globexSecret), WithIssuer("globex")),
+		"globex leeway":  sign(WithSecret(globexSecret), WithIssuer("globex"), past),
+		"tenant default": sign(WithSecret(globexSecret), WithIssuer("globex"), WithAudience("any")),
+	}
+	for name, token := range valid {
+		if _, err := verifier.Parse(token); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	invalid := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"other tenant secret", sign(WithSecret(globexSecret), WithIssuer("acme"), WithAudience("acme-api")), ErrTokenBadSignature},
+		{"tenant audience", sign(WithSecret(acmeSecret), WithIssuer("acme"), WithAudience("globex-api")), ErrTokenBadAudience},
+		{"tenant leeway", sign(WithSecret(acmeSecret), WithIssuer("acme"), WithAudience("acme-api"), past), ErrTokenExpired},
+		{"unknown issuer", sign(WithSecret(acmeSecret), WithIssuer("initech")), ErrTokenBadIssuer},
+		{"unknown kid", sign(WithSecret(globexSecret), WithIssuer("globex"), WithHeader("kid", "hs-9")), ErrTokenUnknownKey},
+		// the kid resolves the ed25519 key, but the tenant methods default to HS256
+		{"tenant methods", sign(WithSigner(edMethod, edKey), WithIssuer("globex"), WithHeader("kid", "ed-1")), ErrTokenBadMethod},
+		{"malformed", "not-a-token", ErrTokenMalformed},
+	}
+	for _, c := range invalid {
+		if _, err := verifier.Parse(c.token); !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+func TestKeyResolver_TenantMethods(t *testing.T) {
+	edKey, edMethod, _ := GenerateSigningKey(KeyTypeEd25519)
+	resolver := KeyResolverFunc(func(header map[string]interface{}, claims jwt.MapClaims) (*ResolvedKey, error) {
+		if header["kid"] != "ed-1" {
+			return nil, errors.New("unknown key")
+		}
+		return &ResolvedKey{Key: edKey.Public(), AllowedMethods: []string{edMethod.Alg()}}, nil
+	})
+	verifier, err := NewVerifier(ParseOptions{KeyResolver: resolver})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _ := NewToken(WithSigner(edMethod, edKey), WithHeader("kid", "ed-1"))
+	if _, err := verifier.Parse(token); err != nil {
+		t.Fatalf("EdDSA token of the resolved key: %v", err)
+	}
+
+	token, _ = NewToken(WithSecret([]byte("s3cr3t")))
+	if _, err := verifier.Parse(token); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("resolver errors should map to ErrTokenInvalid, got %v", err)
+	}
+}
+
+func TestKeyResolver_ParserCache(t *testing.T) {
+	verifier, err := NewVerifier(ParseOptions{KeyResolver: TenantResolver{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := verifier.parserFor(time.Minute, []string{"acme-api"})
+	if verifier.parserFor(time.Minute, []string{"acme-api"}) != parser {
+		t.Fatal("the parser of the same rules should be cached")
+	}
+	if verifier.parserFor(time.Second, []string{"acme-api"}) == parser || verifier.parserFor(time.Minute, nil) == parser {
+		t.Fatal("other rules should get their own parser")
+	}
+
+	if bearer := NewBearerError(ErrTokenUnknownKey); bearer.Status != http.StatusUnauthorized || bearer.Code != BearerInvalidToken {
+		t.Fatalf("unknown key: got %+v", bearer)
+	}
+}
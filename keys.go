@@ -0,0 +1,109 @@
+package gohelpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key types of `GenerateSigningKey`.
+const (
+	KeyTypeEd25519 = "ed25519" // EdDSA
+	KeyTypeECDSA   = "ecdsa"   // ES256, P-256 curve
+	KeyTypeRSA     = "rsa"     // RS256, 2048 bits
+)
+
+// MinSecretKeySize is the minimum size in bytes of `GenerateRandomKey`, 32 bytes match the HS256 hash size.
+const MinSecretKeySize = 32
+
+// GenerateRandomKey returns a random secret of size bytes from crypto/rand, to sign HMAC tokens, e.g. the SECRET_KEY of the `.env` file.
+func GenerateRandomKey(size int) ([]byte, error) {
+	if size < MinSecretKeySize {
+		return nil, fmt.Errorf("the key size must be at least %d bytes, got %d", MinSecretKeySize, size)
+	}
+
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/*
+GenerateSigningKey returns a new private key of keyType (`KeyTypeEd25519`, `KeyTypeECDSA` or `KeyTypeRSA`) and its JWT signing method,
+ready for `WithSigner`:
+
+	key, method, err := gohelpers.GenerateSigningKey(gohelpers.KeyTypeEd25519)
+	token, err := gohelpers.NewToken(gohelpers.WithSigner(method, key), gohelpers.WithSubject("42"))
+
+The verifiers need `key.Public()`.
+*/
+func GenerateSigningKey(keyType string) (crypto.Signer, jwt.SigningMethod, error) {
+	switch keyType {
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, jwt.SigningMethodEdDSA, err
+	case KeyTypeECDSA:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		return key, jwt.SigningMethodES256, err
+	case KeyTypeRSA:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		return key, jwt.SigningMethodRS256, err
+	default:
+		return nil, nil, fmt.Errorf("unknown key type %q, one of: %s, %s, %s", keyType, KeyTypeEd25519, KeyTypeECDSA, KeyTypeRSA)
+	}
+}
+
+// MarshalPrivateKeyPEM encodes a private key as a PKCS #8 "PRIVATE KEY" PEM block, readable by `ParsePrivateKeyPEM` and openssl.
+func MarshalPrivateKeyPEM(key crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// MarshalPublicKeyPEM encodes a public key as a PKIX "PUBLIC KEY" PEM block, to share with the services that verify the tokens.
+func MarshalPublicKeyPEM(key crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM decodes a PKCS #8 "PRIVATE KEY" PEM block, e.g. of `gohelpers keygen --type ed25519 --out key.pem`.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New(`no "PRIVATE KEY" PEM block found`)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM decodes a PKIX "PUBLIC KEY" PEM block, the result is a `ParseOptions.Key`.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New(`no "PUBLIC KEY" PEM block found`)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
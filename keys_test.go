@@ -0,0 +1,58 @@
+package gohelpers
+
+import (
+	"testing"
+)
+
+func TestGenerateRandomKey(t *testing.T) {
+	a, err := GenerateRandomKey(64)
+	b, _ := GenerateRandomKey(64)
+	if err != nil || len(a) != 64 || string(a) == string(b) {
+		t.Fatalf("GenerateRandomKey(64) = %x, %v", a, err)
+	}
+	if _, err := GenerateRandomKey(16); err == nil {
+		t.Fatal("expected an error for a 16 bytes key")
+	}
+}
+
+func TestGenerateSigningKey(t *testing.T) {
+	for _, keyType := range []string{KeyTypeEd25519, KeyTypeECDSA, KeyTypeRSA} {
+		key, method, err := GenerateSigningKey(keyType)
+		if err != nil {
+			t.Fatalf("%s: %v", keyType, err)
+		}
+
+		privatePEM, err := MarshalPrivateKeyPEM(key)
+		if err != nil {
+			t.Fatalf("%s: %v", keyType, err)
+		}
+		publicPEM, _ := MarshalPublicKeyPEM(key.Public())
+		private, err := ParsePrivateKeyPEM(privatePEM)
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKeyPEM: %v", keyType, err)
+		}
+		public, err := ParsePublicKeyPEM(publicPEM)
+		if err != nil {
+			t.Fatalf("%s: ParsePublicKeyPEM: %v", keyType, err)
+		}
+
+		token, err := NewToken(WithSigner(method, private), WithSubject("42"))
+		if err != nil {
+			t.Fatalf("%s: NewToken: %v", keyType, err)
+		}
+		verifier, err := NewVerifier(ParseOptions{Key: public, AllowedMethods: []string{method.Alg()}})
+		if err != nil {
+			t.Fatalf("%s: NewVerifier: %v", keyType, err)
+		}
+		if _, err := verifier.Verify(token); err != nil {
+			t.Fatalf("%s: the public key doesn't verify the token: %v", keyType, err)
+		}
+	}
+
+	if _, _, err := GenerateSigningKey("dsa"); err == nil {
+		t.Fatal("expected an error for an unknown key type")
+	}
+	if _, err := ParsePrivateKeyPEM([]byte("not a pem")); err == nil {
+		t.Fatal("expected an error for a non PEM private key")
+	}
+}
@@ -0,0 +1,187 @@
+package gohelpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey int
+
+const (
+	tokenContextKey contextKey = iota
+	claimsContextKey
+	sourceContextKey
+	apiKeyContextKey
+)
+
+// AuthOptions config for the `Authenticate` middleware.
+type AuthOptions struct {
+	ParseOptions // how the token is extracted and verified
+
+	// Paths that don't require a token. An entry ending with "*" matches every path with that prefix,
+	// e.g. "/public/*", otherwise the request path must be equal to the entry.
+	PublicPaths []string
+	// Skip is a custom check, when it returns true the request is passed to the next handler without auth.
+	Skip func(r *http.Request) bool
+	// Optional lets requests without a token through, requests with an invalid token are still rejected.
+	Optional bool
+	// Refresh is optional, it re-issues the tokens that are about to expire, see `SlidingSession`. An invalid config rejects the requests.
+	Refresh *SlidingSession
+	// APIKeys is optional, it accepts API keys too (X-API-Key header or `Authorization: Bearer gh_...`), see `APIKeyFromContext`.
+	// A valid API key wins over the JWT, an invalid X-API-Key falls back to the JWT of the Authorization header, if any.
+	APIKeys *APIKeys
+	// ErrorHandler writes the response when the auth fails. Default: `WriteBearerError`.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+/*
+Authenticate returns a net/http middleware that verifies the request token, the same way `ParseFromRequest` does.
+Invalid requests are rejected with 401, valid ones are passed to the next handler with the verified token and its claims
+stored in the request context. Use `TokenFromContext` and `ClaimsFromContext` to read them back.
+With `AuthOptions.APIKeys`, the requests of machine clients could carry an API key instead, its `APIKeyClaims` are stored
+as the claims, thus the same handlers and `RequireScopes` serve both.
+Works with any router that accepts `func(http.Handler) http.Handler` (chi, gorilla/mux, ...).
+*/
+func Authenticate(opts AuthOptions) func(http.Handler) http.Handler {
+	errorHandler := opts.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = WriteBearerError
+	}
+
+	verifier, verifierErr := NewVerifier(opts.ParseOptions)
+	if verifierErr == nil && opts.Refresh != nil {
+		verifierErr = opts.Refresh.validate(opts.ParseOptions)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isPublicPath(r.URL.Path, opts.PublicPaths) || (opts.Skip != nil && opts.Skip(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var apiKeyErr error
+			if opts.APIKeys != nil {
+				if plain := opts.APIKeys.extract(r); plain != "" {
+					key, err := opts.APIKeys.Verify(plain)
+					if err == nil {
+						next.ServeHTTP(w, r.WithContext(ContextWithAPIKey(r.Context(), key)))
+						return
+					}
+					if !opts.APIKeys.bearerJWT(r) {
+						errorHandler(w, r, err)
+						return
+					}
+					apiKeyErr = err
+				}
+			}
+
+			if verifierErr != nil {
+				errorHandler(w, r, verifierErr)
+				return
+			}
+
+			token, source, err := verifier.fromRequest(r)
+			if err != nil {
+				if errors.Is(err, ErrTokenMissing) && apiKeyErr != nil {
+					// the extractor doesn't read the Authorization header, the invalid API key is the only credential
+					err = apiKeyErr
+				} else if opts.Optional && errors.Is(err, ErrTokenMissing) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				errorHandler(w, r, err)
+				return
+			}
+
+			if opts.Refresh != nil {
+				if err := opts.Refresh.refresh(w, r, token, opts.ParseOptions); err != nil {
+					errorHandler(w, r, err)
+					return
+				}
+			}
+
+			ctx := context.WithValue(ContextWithToken(r.Context(), token), sourceContextKey, source)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ContextWithToken returns a copy of ctx that holds the token and its claims.
+func ContextWithToken(ctx context.Context, token *jwt.Token) context.Context {
+	ctx = context.WithValue(ctx, tokenContextKey, token)
+	return context.WithValue(ctx, claimsContextKey, token.Claims)
+}
+
+// TokenFromContext returns the verified token stored by `Authenticate`, if any.
+func TokenFromContext(ctx context.Context) (*jwt.Token, bool) {
+	token, ok := ctx.Value(tokenContextKey).(*jwt.Token)
+	return token, ok && token != nil
+}
+
+// TokenSourceFromContext returns where `Authenticate` read the token from: header, cookie, query... or api_key.
+func TokenSourceFromContext(ctx context.Context) (TokenSource, bool) {
+	source, ok := ctx.Value(sourceContextKey).(TokenSource)
+	return source, ok
+}
+
+/*
+ClaimsFromContext returns the claims stored by `Authenticate` as T.
+T could be `jwt.MapClaims`, or any custom claims struct (or a pointer to it) that matches the token payload:
+
+	claims, ok := gohelpers.ClaimsFromContext[jwtCustomClaims](r.Context())
+
+The custom claims are decoded from the verified token payload, with `json.Number` for the untyped numbers, thus large
+integers keep their precision.
+*/
+func ClaimsFromContext[T any](ctx context.Context) (T, bool) {
+	var zero T
+
+	claims := ctx.Value(claimsContextKey)
+	if claims == nil {
+		return zero, false
+	}
+	if typed, ok := claims.(T); ok {
+		return typed, true
+	}
+
+	var out T
+	if token, ok := TokenFromContext(ctx); ok && token.Raw != "" && !isAPIKeyContext(ctx) {
+		parts := strings.Split(token.Raw, ".")
+		if len(parts) != 3 || decodeSegmentJSON(jwt.NewParser(), parts[1], &out) != nil {
+			return zero, false
+		}
+		return out, true
+	}
+	if err := CastJwtClaimsToCustomClaims(claims, &out); err != nil {
+		return zero, false
+	}
+
+	return out, true
+}
+
+// isAPIKeyContext reports whether the stored claims are the ones of an API key rather than a token.
+func isAPIKeyContext(ctx context.Context) bool {
+	source, _ := TokenSourceFromContext(ctx)
+	return source == SourceAPIKey
+}
+
+func isPublicPath(path string, publicPaths []string) bool {
+	for _, p := range publicPaths {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+			continue
+		}
+		if path == p {
+			return true
+		}
+	}
+
+	return false
+}
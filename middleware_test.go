@@ -0,0 +1,131 @@
+package gohelpers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func protectedHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := TokenFromContext(r.Context()); !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		claims, ok := ClaimsFromContext[jwtCustomClaims](r.Context())
+		if !ok || claims.Username != "johnDoe" {
+			t.Errorf("unexpected claims in context: %+v, ok=%v", claims, ok)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	secret := []byte("s3cr3t")
+	cc := createCustomClaim(time.Now().Add(2 * time.Minute))
+	tok, _ := GenerateJwtToken(secret, &cc)
+
+	handler := Authenticate(AuthOptions{
+		ParseOptions: ParseOptions{Secret: secret},
+		PublicPaths:  []string{"/health", "/public/*"},
+	})(protectedHandler(t))
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"valid token", "/api", "Bearer " + tok, http.StatusOK},
+		{"missing token", "/api", "", http.StatusUnauthorized},
+		{"invalid token", "/api", "Bearer " + tok + "x", http.StatusUnauthorized},
+		{"public path", "/health", "", http.StatusNoContent},
+		{"public prefix", "/public/logo.png", "", http.StatusNoContent},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "http://x.local"+c.path, nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != c.want {
+			t.Errorf("%s: got status %d, want %d", c.name, rec.Code, c.want)
+		}
+	}
+}
+
+func TestAuthenticate_OptionalAndCustomErrorHandler(t *testing.T) {
+	secret := []byte("s3cr3t")
+	var handlerErr error
+
+	handler := Authenticate(AuthOptions{
+		ParseOptions: ParseOptions{Secret: secret},
+		Optional:     true,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			handlerErr = err
+			w.WriteHeader(http.StatusTeapot)
+		},
+	})(protectedHandler(t))
+
+	// no token: passed through without auth
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://x.local/api", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("optional auth without token: got %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	// bad token: still rejected
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("Authorization", "Bearer not.a.token")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTeapot || handlerErr == nil {
+		t.Fatalf("optional auth with bad token: got %d, err %v", rec.Code, handlerErr)
+	}
+}
+
+func TestClaimsFromContext_MapClaims(t *testing.T) {
+	secret := []byte("s3cr3t")
+	tok, _ := GenerateJwtToken(secret, jwt.MapClaims{"sub": "42", "exp": time.Now().Add(time.Minute).Unix()})
+
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	token, err := ParseFromRequest(req, ParseOptions{Secret: secret})
+	if err != nil {
+		t.Fatalf("ParseFromRequest failed: %v", err)
+	}
+
+	claims, ok := ClaimsFromContext[jwt.MapClaims](ContextWithToken(req.Context(), token))
+	if !ok || claims["sub"] != "42" {
+		t.Fatalf("unexpected claims: %v, ok=%v", claims, ok)
+	}
+}
+
+func TestClaimsFromContext_LargeIntegers(t *testing.T) {
+	secret := []byte("s3cr3t")
+	tok, _ := NewToken(WithSecret(secret), WithClaim("id", int64(9007199254740993)))
+	verifier, _ := NewVerifier(ParseOptions{Secret: secret})
+	token, err := verifier.Parse(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := ContextWithToken(httptest.NewRequest(http.MethodGet, "/", nil).Context(), token)
+
+	typed, ok := ClaimsFromContext[struct {
+		ID int64 `json:"id"`
+	}](ctx)
+	if !ok || typed.ID != 9007199254740993 {
+		t.Fatalf("typed claims lost precision: %+v, ok=%v", typed, ok)
+	}
+	untyped, ok := ClaimsFromContext[map[string]interface{}](ctx)
+	if !ok || fmt.Sprint(untyped["id"]) != "9007199254740993" {
+		t.Fatalf("untyped claims lost precision: %v, ok=%v", untyped["id"], ok)
+	}
+}
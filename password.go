@@ -0,0 +1,556 @@
+package gohelpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+PasswordHasher hashes passwords with one algorithm. The hashes are self-describing: they carry the algorithm and its
+parameters, thus `VerifyHashedPassword` verifies a hash of any hasher, whatever the current `DefaultPasswordHasher` is.
+*/
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password, with a random salt.
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the hash, the error is for a hash it can't decode.
+	Verify(password, hashed string) (bool, error)
+	// Supports reports whether the hash is of the hasher algorithm, e.g. "$argon2id$..." for `Argon2idHasher`.
+	Supports(hashed string) bool
+	// NeedsRehash reports whether a hash of the hasher algorithm has weaker parameters than the hasher ones, e.g. a lower bcrypt cost.
+	NeedsRehash(hashed string) bool
+}
+
+/*
+DefaultPasswordHasher is the hasher of `HashPassword`, bcrypt with `bcrypt.DefaultCost` (and `BcryptHasher.PreHash`) by
+default. Set it in main to move the new hashes to another algorithm, the existing ones are still verified:
+
+	gohelpers.DefaultPasswordHasher = gohelpers.Argon2idHasher{}
+*/
+var DefaultPasswordHasher PasswordHasher = BcryptHasher{PreHash: true}
+
+var (
+	// ErrPasswordMismatch is returned by `CheckHashedPassword` for a wrong password.
+	ErrPasswordMismatch = errors.New("password mismatch")
+	// ErrPasswordHashMalformed is returned for a hash of a known algorithm that can't be decoded, e.g. truncated or corrupted.
+	ErrPasswordHashMalformed = errors.New("malformed password hash")
+	// ErrPasswordHashUnsupported is returned for a hash of an unknown algorithm.
+	ErrPasswordHashUnsupported = errors.New("unsupported password hash algorithm")
+	// ErrPasswordHasherMisconfigured is returned when the hasher itself can't verify the hash, e.g. a missing pepper version.
+	ErrPasswordHasherMisconfigured = errors.New("password hasher misconfigured")
+)
+
+// passwordHashers are the algorithms `VerifyHashedPassword` detects.
+var passwordHashers = []PasswordHasher{BcryptHasher{}, Argon2idHasher{}, ScryptHasher{}}
+
+// passwordHasherOf returns the hasher of the hash algorithm, nil when it's not supported.
+func passwordHasherOf(hashed string) PasswordHasher {
+	if DefaultPasswordHasher != nil && DefaultPasswordHasher.Supports(hashed) {
+		return DefaultPasswordHasher
+	}
+	for _, hasher := range passwordHashers {
+		if hasher.Supports(hashed) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+/*
+BcryptHasher hashes with bcrypt, in its standard "$2a$<cost>$..." format, the format of the existing `HashPassword` hashes.
+bcrypt only reads the first 72 bytes of a password, and rejects longer ones. With PreHash, a longer password is first
+hashed with HMAC-SHA256, and its hash is marked "$bcrypt-sha256$2a$...". The passwords up to 72 bytes keep the standard format.
+*/
+type BcryptHasher struct {
+	Cost    int  // default: bcrypt.DefaultCost
+	PreHash bool // pre-hash the passwords longer than 72 bytes instead of rejecting them
+}
+
+const bcryptPreHashPrefix = "$bcrypt-sha256"
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	if len(password) <= 72 || !h.PreHash {
+		return HashPasswordWithCost(password, h.withDefaults().Cost)
+	}
+
+	hashed, err := HashPasswordWithCost(bcryptPreHash(password), h.withDefaults().Cost)
+	if err != nil {
+		return "", err
+	}
+	return bcryptPreHashPrefix + hashed, nil
+}
+
+func (h BcryptHasher) Verify(password, hashed string) (bool, error) {
+	if inner, ok := strings.CutPrefix(hashed, bcryptPreHashPrefix); ok {
+		password, hashed = bcryptPreHash(password), inner
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h BcryptHasher) NeedsRehash(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(strings.TrimPrefix(hashed, bcryptPreHashPrefix)))
+	return err != nil || cost < h.withDefaults().Cost
+}
+
+func (h BcryptHasher) withDefaults() BcryptHasher {
+	if h.Cost == 0 {
+		h.Cost = bcrypt.DefaultCost
+	}
+	return h
+}
+
+func (h BcryptHasher) Supports(hashed string) bool {
+	hashed = strings.TrimPrefix(hashed, bcryptPreHashPrefix)
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hashed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bcryptPreHash returns the base64 HMAC-SHA256 of a long password, 44 bytes that bcrypt reads entirely.
+// The HMAC key keeps the result apart from the plain SHA-256 hashes of other leaks.
+func bcryptPreHash(password string) string {
+	mac := hmac.New(sha256.New, []byte("gohelpers bcrypt pre-hash"))
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// The maximum parameters of the stored hashes, `Verify` rejects the ones above them instead of spending minutes or GiBs on a
+// tampered hash.
+const (
+	maxArgon2Memory     = 1 << 20 // KiB, 1 GiB
+	maxArgon2Iterations = 64
+	maxScryptNR         = 1 << 23 // N*r, 1 GiB of memory
+	maxScryptP          = 16
+	maxPasswordKeyLen   = 128 // bytes
+)
+
+/*
+Argon2idHasher hashes with argon2id, in the PHC string format "$argon2id$v=19$m=<KiB>,t=<iterations>,p=<threads>$<salt>$<hash>".
+The zero value uses the OWASP recommended parameters: 19 MiB of memory, 2 iterations and 1 thread.
+*/
+type Argon2idHasher struct {
+	Memory     uint32 // KiB, default: 19456
+	Iterations uint32 // default: 2
+	Threads    uint8  // default: 1
+	SaltLength uint32 // bytes, default: 16
+	KeyLength  uint32 // bytes, default: 32
+}
+
+func (h Argon2idHasher) withDefaults() Argon2idHasher {
+	if h.Memory == 0 {
+		h.Memory = 19456
+	}
+	if h.Iterations == 0 {
+		h.Iterations = 2
+	}
+	if h.Threads == 0 {
+		h.Threads = 1
+	}
+	if h.SaltLength == 0 {
+		h.SaltLength = 16
+	}
+	if h.KeyLength == 0 {
+		h.KeyLength = 32
+	}
+	return h
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	h = h.withDefaults()
+	salt, err := passwordSalt(h.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Threads, h.KeyLength)
+	return phcHash{
+		id:      "argon2id",
+		version: argon2.Version,
+		params:  [][2]string{{"m", fmt.Sprint(h.Memory)}, {"t", fmt.Sprint(h.Iterations)}, {"p", fmt.Sprint(h.Threads)}},
+		salt:    salt,
+		hash:    key,
+	}.String(), nil
+}
+
+func (h Argon2idHasher) Verify(password, hashed string) (bool, error) {
+	phc, err := parsePHC(hashed, "argon2id")
+	if err != nil {
+		return false, err
+	}
+	if phc.version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2 version %d", phc.version)
+	}
+	params, err := phc.uints("m", "t", "p")
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case params[0] > maxArgon2Memory:
+		return false, fmt.Errorf("argon2 memory must be at most %d KiB", maxArgon2Memory)
+	case params[1] > maxArgon2Iterations:
+		return false, fmt.Errorf("argon2 iterations must be at most %d", maxArgon2Iterations)
+	case params[2] > 255:
+		return false, errors.New("argon2 threads must be at most 255")
+	case len(phc.hash) > maxPasswordKeyLen:
+		return false, fmt.Errorf("argon2 key length must be at most %d bytes", maxPasswordKeyLen)
+	}
+
+	key := argon2.IDKey([]byte(password), phc.salt, uint32(params[1]), uint32(params[0]), uint8(params[2]), uint32(len(phc.hash)))
+	return subtle.ConstantTimeCompare(key, phc.hash) == 1, nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hashed string) bool {
+	h = h.withDefaults()
+	phc, err := parsePHC(hashed, "argon2id")
+	if err != nil || phc.version != argon2.Version {
+		return true
+	}
+	params, err := phc.uints("m", "t", "p")
+	if err != nil {
+		return true
+	}
+	return params[0] < uint64(h.Memory) || params[1] < uint64(h.Iterations) || params[2] < uint64(h.Threads) ||
+		len(phc.salt) < int(h.SaltLength) || len(phc.hash) < int(h.KeyLength)
+}
+
+func (h Argon2idHasher) Supports(hashed string) bool {
+	return strings.HasPrefix(hashed, "$argon2id$")
+}
+
+/*
+ScryptHasher hashes with scrypt, in the PHC string format "$scrypt$ln=<log2 N>,r=<block size>,p=<parallelism>$<salt>$<hash>".
+The zero value uses the OWASP recommended parameters: N=2^17, r=8 and p=1.
+*/
+type ScryptHasher struct {
+	LogN       uint8  // log2 of the CPU/memory cost N, default: 17
+	R          int    // default: 8
+	P          int    // default: 1
+	SaltLength uint32 // bytes, default: 16
+	KeyLength  uint32 // bytes, default: 32
+}
+
+func (h ScryptHasher) withDefaults() ScryptHasher {
+	if h.LogN == 0 {
+		h.LogN = 17
+	}
+	if h.R == 0 {
+		h.R = 8
+	}
+	if h.P == 0 {
+		h.P = 1
+	}
+	if h.SaltLength == 0 {
+		h.SaltLength = 16
+	}
+	if h.KeyLength == 0 {
+		h.KeyLength = 32
+	}
+	return h
+}
+
+func (h ScryptHasher) Hash(password string) (string, error) {
+	h = h.withDefaults()
+	salt, err := passwordSalt(h.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<h.LogN, h.R, h.P, int(h.KeyLength))
+	if err != nil {
+		return "", err
+	}
+	return phcHash{
+		id:     "scrypt",
+		params: [][2]string{{"ln", fmt.Sprint(h.LogN)}, {"r", fmt.Sprint(h.R)}, {"p", fmt.Sprint(h.P)}},
+		salt:   salt,
+		hash:   key,
+	}.String(), nil
+}
+
+func (h ScryptHasher) Verify(password, hashed string) (bool, error) {
+	phc, err := parsePHC(hashed, "scrypt")
+	if err != nil {
+		return false, err
+	}
+	params, err := phc.uints("ln", "r", "p")
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case params[0] > 30:
+		return false, errors.New("scrypt ln must be between 1 and 30")
+	case (uint64(1)<<params[0])*params[1] > maxScryptNR:
+		return false, fmt.Errorf("scrypt N*r must be at most %d", maxScryptNR)
+	case params[2] > maxScryptP:
+		return false, fmt.Errorf("scrypt p must be at most %d", maxScryptP)
+	case len(phc.hash) > maxPasswordKeyLen:
+		return false, fmt.Errorf("scrypt key length must be at most %d bytes", maxPasswordKeyLen)
+	}
+
+	key, err := scrypt.Key([]byte(password), phc.salt, 1<<params[0], int(params[1]), int(params[2]), len(phc.hash))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, phc.hash) == 1, nil
+}
+
+func (h ScryptHasher) NeedsRehash(hashed string) bool {
+	h = h.withDefaults()
+	phc, err := parsePHC(hashed, "scrypt")
+	if err != nil {
+		return true
+	}
+	params, err := phc.uints("ln", "r", "p")
+	if err != nil {
+		return true
+	}
+	return params[0] < uint64(h.LogN) || params[1] < uint64(h.R) || params[2] < uint64(h.P) ||
+		len(phc.salt) < int(h.SaltLength) || len(phc.hash) < int(h.KeyLength)
+}
+
+func (h ScryptHasher) Supports(hashed string) bool {
+	return strings.HasPrefix(hashed, "$scrypt$")
+}
+
+/*
+VerifyAndRehash verifies the password, and when it matches a hash of an outdated algorithm or weaker parameters than the
+`DefaultPasswordHasher`, it returns a new hash of the password to save, e.g. at login:
+
+	ok, newHash, err := gohelpers.VerifyAndRehash(password, user.PasswordHash)
+	if ok && newHash != "" {
+		users.UpdatePasswordHash(user.ID, newHash)
+	}
+
+newHash is empty when the hash is up to date, or when `DefaultPasswordHasher` is nil. The error is for a hash that can't be
+verified or a failed rehash.
+*/
+func VerifyAndRehash(plain, hashed string) (bool, string, error) {
+	err := CheckHashedPassword(plain, hashed)
+	if errors.Is(err, ErrPasswordMismatch) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	current := DefaultPasswordHasher
+	if current == nil || (current.Supports(hashed) && !current.NeedsRehash(hashed)) {
+		return true, "", nil
+	}
+
+	newHash, err := current.Hash(plain)
+	if err != nil {
+		return true, "", err
+	}
+	return true, newHash, nil
+}
+
+/*
+CheckHashedPassword is `VerifyHashedPassword` with an error: nil when the password matches, otherwise `ErrPasswordMismatch`
+for a wrong password, `ErrPasswordHashMalformed` for a broken hash, `ErrPasswordHashUnsupported` for an unknown algorithm, or
+`ErrPasswordHasherMisconfigured` for a hasher that can't verify it. Log the last three, they mean a corrupted users table or
+a missing hasher, not a failed login.
+*/
+func CheckHashedPassword(plain, hashed string) error {
+	hasher := passwordHasherOf(hashed)
+	if hasher == nil {
+		return ErrPasswordHashUnsupported
+	}
+
+	ok, err := hasher.Verify(plain, hashed)
+	if errors.Is(err, ErrPasswordHasherMisconfigured) || errors.Is(err, ErrPasswordHashUnsupported) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPasswordHashMalformed, err)
+	}
+	if !ok {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+const dummyPasswordPlain = "gohelpers dummy password"
+
+// dummyPassword is the dummy hash of `VerifyAgainstDummy`. It starts as a precomputed hash of the initial
+// `DefaultPasswordHasher`, the one of another default hasher is computed in the background.
+var dummyPassword = struct {
+	sync.Mutex
+	hasher    PasswordHasher
+	hash      string
+	computing PasswordHasher // the hasher of the last computed (or failed) dummy hash
+	pending   bool
+}{
+	hasher: BcryptHasher{PreHash: true},
+	hash:   "$2a$10$ze9yvPyrHvwYAXslKCOkjOXkSSVUAyz4D..A4gkMnTqoyokj.uNt6",
+}
+
+/*
+VerifyAgainstDummy verifies the password against a dummy hash of the `DefaultPasswordHasher`, and always returns
+`ErrPasswordMismatch`. Call it when the user doesn't exist, thus the login takes the same time for unknown users and wrong
+passwords, and the response time doesn't tell which usernames exist:
+
+	user, err := users.FindByEmail(email)
+	if err != nil {
+		gohelpers.VerifyAgainstDummy(password)
+		return ErrInvalidCredentials
+	}
+	if err := gohelpers.CheckHashedPassword(password, user.PasswordHash); err != nil {
+		return ErrInvalidCredentials
+	}
+
+The dummy hash of the default bcrypt hasher is precomputed. After the `DefaultPasswordHasher` is changed, the dummy hash of
+the new one is computed in the background, meanwhile (or when it fails) the bcrypt one is verified: a call never skips the
+verify.
+*/
+func VerifyAgainstDummy(plain string) error {
+	hasher, hashed := dummyPasswordHash()
+	hasher.Verify(plain, hashed)
+	return ErrPasswordMismatch
+}
+
+// dummyPasswordHash returns the current dummy hash, and starts the computing of the one of the default hasher when it changed.
+func dummyPasswordHash() (PasswordHasher, string) {
+	dummyPassword.Lock()
+	defer dummyPassword.Unlock()
+
+	current := DefaultPasswordHasher
+	if current != nil && !dummyPassword.pending && !sameHasher(dummyPassword.hasher, current) &&
+		!sameHasher(dummyPassword.computing, current) {
+		dummyPassword.pending, dummyPassword.computing = true, current
+		go func() {
+			hashed, err := current.Hash(dummyPasswordPlain)
+
+			dummyPassword.Lock()
+			defer dummyPassword.Unlock()
+			dummyPassword.pending = false
+			if err == nil {
+				dummyPassword.hasher, dummyPassword.hash = current, hashed
+			}
+		}()
+	}
+	return dummyPassword.hasher, dummyPassword.hash
+}
+
+// sameHasher compares two hashers without panicking on the ones that are not comparable.
+func sameHasher(a, b PasswordHasher) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.ValueOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+func passwordSalt(size uint32) ([]byte, error) {
+	salt := make([]byte, size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// phcHash is a hash in the PHC string format: $<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*]$<salt>$<hash>.
+type phcHash struct {
+	id      string
+	version int // 0 when the hash has no version
+	params  [][2]string
+	salt    []byte
+	hash    []byte
+}
+
+func (p phcHash) String() string {
+	var b strings.Builder
+	b.WriteString("$" + p.id)
+	if p.version != 0 {
+		fmt.Fprintf(&b, "$v=%d", p.version)
+	}
+	if len(p.params) > 0 {
+		pairs := make([]string, len(p.params))
+		for i, kv := range p.params {
+			pairs[i] = kv[0] + "=" + kv[1]
+		}
+		b.WriteString("$" + strings.Join(pairs, ","))
+	}
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.salt))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.hash))
+	return b.String()
+}
+
+func parsePHC(hashed, id string) (*phcHash, error) {
+	fields := strings.Split(hashed, "$")
+	if len(fields) < 4 || fields[0] != "" || fields[1] != id {
+		return nil, fmt.Errorf("not a %s PHC hash", id)
+	}
+
+	phc := &phcHash{id: id}
+	rest := fields[2 : len(fields)-2]
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "v=") {
+		version, err := strconv.Atoi(strings.TrimPrefix(rest[0], "v="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s version", id)
+		}
+		phc.version, rest = version, rest[1:]
+	}
+	if len(rest) > 1 {
+		return nil, fmt.Errorf("invalid %s PHC hash", id)
+	}
+	if len(rest) == 1 {
+		for _, pair := range strings.Split(rest[0], ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid %s parameter %q", id, pair)
+			}
+			phc.params = append(phc.params, [2]string{k, v})
+		}
+	}
+
+	var err error
+	if phc.salt, err = base64.RawStdEncoding.DecodeString(fields[len(fields)-2]); err != nil || len(phc.salt) == 0 {
+		return nil, fmt.Errorf("invalid %s salt", id)
+	}
+	if phc.hash, err = base64.RawStdEncoding.DecodeString(fields[len(fields)-1]); err != nil || len(phc.hash) == 0 {
+		return nil, fmt.Errorf("invalid %s hash", id)
+	}
+
+	return phc, nil
+}
+
+// uints returns the values of the params, they're all required and positive.
+func (p phcHash) uints(names ...string) ([]uint64, error) {
+	values := make([]uint64, len(names))
+	for i, name := range names {
+		found := false
+		for _, kv := range p.params {
+			if kv[0] == name {
+				v, err := strconv.ParseUint(kv[1], 10, 32)
+				if err != nil || v == 0 {
+					return nil, fmt.Errorf("invalid %s parameter %s=%s", p.id, name, kv[1])
+				}
+				values[i], found = v, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("missing %s parameter %s", p.id, name)
+		}
+	}
+	return values, nil
+}
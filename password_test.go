@@ -0,0 +1,262 @@
+package gohelpers
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fast parameters, the defaults are tuned for production
+var testPasswordHashers = map[string]PasswordHasher{
+	"bcrypt":   BcryptHasher{Cost: bcrypt.MinCost},
+	"argon2id": Argon2idHasher{Memory: 64, Iterations: 1},
+	"scrypt":   ScryptHasher{LogN: 4},
+}
+
+func TestPasswordHashers(t *testing.T) {
+	formats := map[string]*regexp.Regexp{
+		"bcrypt":   regexp.MustCompile(`^\$2a\$04\$[./A-Za-z0-9]{53}$`),
+		"argon2id": regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=1,p=1\$[+/A-Za-z0-9]{22}\$[+/A-Za-z0-9]{43}$`),
+		"scrypt":   regexp.MustCompile(`^\$scrypt\$ln=4,r=8,p=1\$[+/A-Za-z0-9]{22}\$[+/A-Za-z0-9]{43}$`),
+	}
+
+	for name, hasher := range testPasswordHashers {
+		hashed, err := hasher.Hash("correct horse")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !formats[name].MatchString(hashed) {
+			t.Errorf("%s: unexpected format %q", name, hashed)
+		}
+		if other, _ := hasher.Hash("correct horse"); other == hashed {
+			t.Errorf("%s: the salt is not random", name)
+		}
+
+		if ok, err := hasher.Verify("correct horse", hashed); !ok || err != nil {
+			t.Errorf("%s: Verify = %v, %v", name, ok, err)
+		}
+		if ok, _ := hasher.Verify("wrong horse", hashed); ok {
+			t.Errorf("%s: a wrong password matched", name)
+		}
+		// the algorithm is detected from the hash
+		if !VerifyHashedPassword("correct horse", hashed) || VerifyHashedPassword("wrong horse", hashed) {
+			t.Errorf("%s: VerifyHashedPassword doesn't detect the algorithm", name)
+		}
+	}
+}
+
+func TestDefaultPasswordHasher(t *testing.T) {
+	old := DefaultPasswordHasher
+	defer func() { DefaultPasswordHasher = old }()
+
+	legacy, _ := HashPassword("secret")
+	DefaultPasswordHasher = testPasswordHashers["argon2id"]
+	hashed, err := HashPassword("secret")
+	if err != nil || !strings.HasPrefix(hashed, "$argon2id$") {
+		t.Fatalf("HashPassword = %q, %v", hashed, err)
+	}
+	if !VerifyHashedPassword("secret", hashed) || !VerifyHashedPassword("secret", legacy) {
+		t.Fatal("the argon2id and legacy bcrypt hashes should both verify")
+	}
+}
+
+func TestPasswordHashers_Malformed(t *testing.T) {
+	hashed, _ := testPasswordHashers["argon2id"].Hash("secret")
+	malformed := []string{
+		"",
+		"plain text",
+		"$argon2id$v=19$m=64,t=1,p=1$",
+		strings.Replace(hashed, "v=19", "v=16", 1),
+		strings.Replace(hashed, "t=1", "t=0", 1),
+		strings.Replace(hashed, ",p=1", "", 1),
+		hashed[:len(hashed)-1] + "!",
+		"$scrypt$ln=99,r=8,p=1$c2FsdA$aGFzaA",
+		"$md5$abc",
+	}
+	for _, h := range malformed {
+		if VerifyHashedPassword("secret", h) {
+			t.Errorf("%q: a malformed hash matched", h)
+		}
+	}
+	if _, err := (Argon2idHasher{}).Verify("secret", strings.Replace(hashed, "t=1", "t=x", 1)); err == nil {
+		t.Error("expected an error for a malformed parameter")
+	}
+}
+
+func TestPasswordHashers_Limits(t *testing.T) {
+	longKey := strings.Repeat("a", 200)
+	tooCostly := []string{
+		"$argon2id$v=19$m=4194304,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=64,t=1000,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$" + longKey,
+		"$scrypt$ln=20,r=64,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$scrypt$ln=4,r=8,p=1000$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$scrypt$ln=4,r=8,p=1$c2FsdHNhbHQ$" + longKey,
+	}
+	for _, h := range tooCostly {
+		if err := CheckHashedPassword("secret", h); !errors.Is(err, ErrPasswordHashMalformed) {
+			t.Errorf("%q: expected ErrPasswordHashMalformed, got %v", h, err)
+		}
+	}
+}
+
+func TestVerifyAndRehash(t *testing.T) {
+	old := DefaultPasswordHasher
+	defer func() { DefaultPasswordHasher = old }()
+
+	weakBcrypt, _ := BcryptHasher{Cost: bcrypt.MinCost}.Hash("secret")
+	DefaultPasswordHasher = BcryptHasher{Cost: bcrypt.MinCost + 1}
+
+	// lower cost than the policy
+	ok, newHash, err := VerifyAndRehash("secret", weakBcrypt)
+	if !ok || err != nil || newHash == "" {
+		t.Fatalf("VerifyAndRehash = %v, %q, %v", ok, newHash, err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(newHash)); cost != bcrypt.MinCost+1 || !VerifyHashedPassword("secret", newHash) {
+		t.Fatalf("unexpected new hash %q", newHash)
+	}
+
+	// up to date
+	if ok, newHash, err := VerifyAndRehash("secret", newHash); !ok || err != nil || newHash != "" {
+		t.Fatalf("up to date hash: %v, %q, %v", ok, newHash, err)
+	}
+
+	// wrong password, no rehash
+	if ok, newHash, _ := VerifyAndRehash("wrong", weakBcrypt); ok || newHash != "" {
+		t.Fatalf("wrong password: %v, %q", ok, newHash)
+	}
+
+	// no default hasher, no rehash
+	DefaultPasswordHasher = nil
+	if ok, newHash, err := VerifyAndRehash("secret", weakBcrypt); !ok || err != nil || newHash != "" {
+		t.Fatalf("nil DefaultPasswordHasher: %v, %q, %v", ok, newHash, err)
+	}
+
+	// outdated algorithm, then weaker argon2id parameters
+	DefaultPasswordHasher = Argon2idHasher{Memory: 128, Iterations: 1}
+	ok, newHash, _ = VerifyAndRehash("secret", weakBcrypt)
+	if !ok || !strings.HasPrefix(newHash, "$argon2id$v=19$m=128,t=1,p=1$") {
+		t.Fatalf("bcrypt to argon2id: %v, %q", ok, newHash)
+	}
+	weakArgon, _ := Argon2idHasher{Memory: 64, Iterations: 1}.Hash("secret")
+	if ok, newHash, _ := VerifyAndRehash("secret", weakArgon); !ok || !strings.Contains(newHash, "m=128") {
+		t.Fatalf("argon2id memory upgrade: %v, %q", ok, newHash)
+	}
+	stronger, _ := Argon2idHasher{Memory: 256, Iterations: 1}.Hash("secret")
+	if _, newHash, _ := VerifyAndRehash("secret", stronger); newHash != "" {
+		t.Fatalf("stronger parameters should be kept, got %q", newHash)
+	}
+
+	if _, _, err := VerifyAndRehash("secret", "$md5$abc"); err == nil {
+		t.Fatal("expected an error for an unsupported hash")
+	}
+}
+
+func TestBcryptHasher_PreHash(t *testing.T) {
+	long := strings.Repeat("correct horse battery staple ", 4) // 116 bytes
+
+	if _, err := (BcryptHasher{Cost: bcrypt.MinCost}).Hash(long); err == nil {
+		t.Fatal("expected an error for a long password without PreHash")
+	}
+
+	hasher := BcryptHasher{Cost: bcrypt.MinCost, PreHash: true}
+	hashed, err := hasher.Hash(long)
+	if err != nil || !strings.HasPrefix(hashed, "$bcrypt-sha256$2a$04$") {
+		t.Fatalf("Hash = %q, %v", hashed, err)
+	}
+	if !VerifyHashedPassword(long, hashed) || hasher.NeedsRehash(hashed) {
+		t.Fatal("the pre-hashed hash should verify and be up to date")
+	}
+	// the bytes after 72 count
+	if VerifyHashedPassword(long[:len(long)-1]+"!", hashed) {
+		t.Fatal("a password that differs after 72 bytes matched")
+	}
+
+	// the short passwords keep the standard format
+	short, _ := hasher.Hash("secret")
+	if !strings.HasPrefix(short, "$2a$04$") || !VerifyHashedPassword("secret", short) {
+		t.Fatalf("unexpected short hash %q", short)
+	}
+}
+
+func TestCheckHashedPassword(t *testing.T) {
+	hashed, _ := testPasswordHashers["bcrypt"].Hash("secret")
+	argon, _ := testPasswordHashers["argon2id"].Hash("secret")
+
+	cases := []struct {
+		name   string
+		plain  string
+		hashed string
+		want   error
+	}{
+		{"match", "secret", hashed, nil},
+		{"mismatch", "wrong", hashed, ErrPasswordMismatch},
+		{"truncated bcrypt", "secret", hashed[:20], ErrPasswordHashMalformed},
+		{"truncated argon2id", "secret", argon[:len(argon)-10] + "!", ErrPasswordHashMalformed},
+		{"unsupported", "secret", "$md5$abc", ErrPasswordHashUnsupported},
+		{"empty", "secret", "", ErrPasswordHashUnsupported},
+	}
+	for _, c := range cases {
+		err := CheckHashedPassword(c.plain, c.hashed)
+		if c.want == nil && err != nil || c.want != nil && !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+
+	// a hasher that can't verify the hash is not a malformed hash
+	old := DefaultPasswordHasher
+	defer func() { DefaultPasswordHasher = old }()
+	DefaultPasswordHasher = PepperedHasher{Hasher: BcryptHasher{Cost: bcrypt.MinCost}, Ring: &PepperRing{Current: "1", Keys: map[string][]byte{"1": []byte("first")}}}
+	peppered, _ := DefaultPasswordHasher.Hash("secret")
+	err := CheckHashedPassword("secret", strings.Replace(peppered, "$pepper$v=1$", "$pepper$v=9$", 1))
+	if !errors.Is(err, ErrPasswordHasherMisconfigured) || errors.Is(err, ErrPasswordHashMalformed) {
+		t.Errorf("unknown pepper version: expected ErrPasswordHasherMisconfigured, got %v", err)
+	}
+
+	// a broken hash is an error of VerifyAndRehash, a wrong password is not
+	if _, _, err := VerifyAndRehash("secret", hashed[:20]); !errors.Is(err, ErrPasswordHashMalformed) {
+		t.Errorf("VerifyAndRehash: expected ErrPasswordHashMalformed, got %v", err)
+	}
+}
+
+func TestVerifyAgainstDummy(t *testing.T) {
+	old := DefaultPasswordHasher
+	defer func() { DefaultPasswordHasher = old }()
+
+	// the precomputed hash is of the initial default hasher
+	if hasher, hashed := dummyPasswordHash(); !sameHasher(hasher, BcryptHasher{PreHash: true}) || CheckHashedPassword(dummyPasswordPlain, hashed) != nil {
+		t.Fatalf("the precomputed dummy hash %q doesn't verify", hashed)
+	}
+
+	for _, hasher := range []PasswordHasher{testPasswordHashers["bcrypt"], testPasswordHashers["scrypt"]} {
+		DefaultPasswordHasher = hasher
+		if err := VerifyAgainstDummy("secret"); !errors.Is(err, ErrPasswordMismatch) {
+			t.Fatalf("expected ErrPasswordMismatch, got %v", err)
+		}
+		// computed in the background
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			current, hashed := dummyPasswordHash()
+			if sameHasher(current, hasher) && hasher.Supports(hashed) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("the dummy hash %q is not of the default hasher", hashed)
+			}
+		}
+	}
+	if err := VerifyAgainstDummy(dummyPasswordPlain); !errors.Is(err, ErrPasswordMismatch) {
+		t.Fatalf("the dummy password matched: %v", err)
+	}
+
+	// a hasher that fails keeps the previous dummy hash
+	DefaultPasswordHasher = PepperedHasher{}
+	VerifyAgainstDummy("secret")
+	time.Sleep(50 * time.Millisecond)
+	if hasher, hashed := dummyPasswordHash(); hasher == nil || hashed == "" {
+		t.Fatal("a failed dummy hash should keep the previous one")
+	}
+}
@@ -0,0 +1,382 @@
+package gohelpers
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordViolationCode identifies a broken rule of a `PasswordPolicy`, it's the key of the localized messages.
+type PasswordViolationCode string
+
+const (
+	PasswordTooShort      PasswordViolationCode = "password_too_short"
+	PasswordTooLong       PasswordViolationCode = "password_too_long"
+	PasswordMissingLower  PasswordViolationCode = "password_missing_lower"
+	PasswordMissingUpper  PasswordViolationCode = "password_missing_upper"
+	PasswordMissingDigit  PasswordViolationCode = "password_missing_digit"
+	PasswordMissingSymbol PasswordViolationCode = "password_missing_symbol"
+	PasswordLowEntropy    PasswordViolationCode = "password_low_entropy"
+	PasswordUserInfo      PasswordViolationCode = "password_user_info"
+	PasswordRepeated      PasswordViolationCode = "password_repeated"
+	PasswordSequence      PasswordViolationCode = "password_sequence"
+	PasswordCommon        PasswordViolationCode = "password_common"
+	PasswordBreached      PasswordViolationCode = "password_breached"
+)
+
+/*
+PasswordPolicyMessages are the messages of the violations by language, "en" and "ar" are built in. A "%d" is replaced by
+the `PasswordViolation.Limit`. Add a language or override a message in main:
+
+	gohelpers.PasswordPolicyMessages["fr"] = map[gohelpers.PasswordViolationCode]string{...}
+*/
+var PasswordPolicyMessages = map[string]map[PasswordViolationCode]string{
+	"en": {
+		PasswordTooShort:      "the password must be at least %d characters long",
+		PasswordTooLong:       "the password must be at most %d characters long",
+		PasswordMissingLower:  "the password must contain a lowercase letter",
+		PasswordMissingUpper:  "the password must contain an uppercase letter",
+		PasswordMissingDigit:  "the password must contain a digit",
+		PasswordMissingSymbol: "the password must contain a symbol",
+		PasswordLowEntropy:    "the password is too easy to guess, use a longer or more varied one",
+		PasswordUserInfo:      "the password must not contain the username or email",
+		PasswordRepeated:      "the password must not contain repeated characters or patterns",
+		PasswordSequence:      "the password must not contain sequences like abcd or 1234",
+		PasswordCommon:        "the password is too common, choose another one",
+		PasswordBreached:      "the password appeared in a data breach, choose another one",
+	},
+	"ar": {
+		PasswordTooShort:      "يجب أن تتكون كلمة المرور من %d أحرف على الأقل",
+		PasswordTooLong:       "يجب ألا تتجاوز كلمة المرور %d حرفًا",
+		PasswordMissingLower:  "يجب أن تحتوي كلمة المرور على حرف صغير",
+		PasswordMissingUpper:  "يجب أن تحتوي كلمة المرور على حرف كبير",
+		PasswordMissingDigit:  "يجب أن تحتوي كلمة المرور على رقم",
+		PasswordMissingSymbol: "يجب أن تحتوي كلمة المرور على رمز",
+		PasswordLowEntropy:    "كلمة المرور سهلة التخمين، استخدم كلمة أطول أو أكثر تنوعًا",
+		PasswordUserInfo:      "يجب ألا تحتوي كلمة المرور على اسم المستخدم أو البريد الإلكتروني",
+		PasswordRepeated:      "يجب ألا تحتوي كلمة المرور على أحرف أو أنماط مكررة",
+		PasswordSequence:      "يجب ألا تحتوي كلمة المرور على تسلسلات مثل abcd أو 1234",
+		PasswordCommon:        "كلمة المرور هذه شائعة جدًا، اختر كلمة أخرى",
+		PasswordBreached:      "ظهرت كلمة المرور هذه في تسريب بيانات، اختر كلمة أخرى",
+	},
+}
+
+// PasswordViolation is a broken rule of a `PasswordPolicy`.
+type PasswordViolation struct {
+	Code  PasswordViolationCode
+	Limit int // the limit of the rule when it has one, e.g. the min length of `PasswordTooShort`
+}
+
+// Message returns the message of the violation in the language of `PasswordPolicyMessages`, English when the language or message is missing.
+func (v PasswordViolation) Message(lang string) string {
+	format, ok := PasswordPolicyMessages[lang][v.Code]
+	if !ok {
+		if format, ok = PasswordPolicyMessages["en"][v.Code]; !ok {
+			return string(v.Code)
+		}
+	}
+	if strings.Contains(format, "%d") {
+		return fmt.Sprintf(format, v.Limit)
+	}
+	return format
+}
+
+/*
+PasswordPolicyError is returned by `PasswordPolicy.Validate` with all the broken rules, not only the first one.
+BreachedErr is the error of the `PasswordPolicy.Breached` source, the password was not checked against the breaches.
+*/
+type PasswordPolicyError struct {
+	Violations  []PasswordViolation
+	BreachedErr error
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := e.Messages("en")
+	if e.BreachedErr != nil {
+		messages = append(messages, "breached passwords check failed: "+e.BreachedErr.Error())
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Unwrap returns BreachedErr.
+func (e *PasswordPolicyError) Unwrap() error {
+	return e.BreachedErr
+}
+
+// Messages returns the messages of the violations in the language, e.g. to show them under the signup form.
+func (e *PasswordPolicyError) Messages(lang string) []string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message(lang)
+	}
+	return messages
+}
+
+// Has reports whether the code is one of the violations.
+func (e *PasswordPolicyError) Has(code PasswordViolationCode) bool {
+	for _, v := range e.Violations {
+		if v.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+PasswordPolicy validates the new passwords, e.g. at signup, before `HashPassword`:
+
+	policy := gohelpers.PasswordPolicy{MinLength: 10, MinEntropy: 50}
+	if err := policy.Validate(password, username, email); err != nil {
+		var policyErr *gohelpers.PasswordPolicyError
+		errors.As(err, &policyErr)
+		return policyErr.Messages("ar")
+	}
+	hashed, err := gohelpers.HashPassword(password)
+
+The lengths are counted in characters, not bytes. The zero value checks the lengths, the user info, the repeated
+characters and sequences, and the embedded list of common passwords. The character classes and entropy are opt-in,
+NIST recommends the length and the common passwords check over composition rules.
+*/
+type PasswordPolicy struct {
+	MinLength     int     // default: 8
+	MaxLength     int     // default: 64
+	RequireLower  bool    // at least a lowercase letter
+	RequireUpper  bool    // at least an uppercase letter
+	RequireDigit  bool    // at least a digit
+	RequireSymbol bool    // at least a character that is not a letter, digit or space
+	MinEntropy    float64 // min estimated bits, 0: not checked
+	MaxRepeat     int     // longest allowed run of a character ("aaa") or sequence ("123", "cba"), default: 3
+	AllowUserInfo bool    // don't check the password against the user inputs of `Validate`
+	AllowCommon   bool    // don't check the password against the common passwords list
+
+	// Breached is optional, it rejects the passwords found in data breaches, see `BreachedPasswords`.
+	Breached *BreachedPasswords
+}
+
+func (p PasswordPolicy) withDefaults() PasswordPolicy {
+	if p.MinLength == 0 {
+		p.MinLength = 8
+	}
+	if p.MaxLength == 0 {
+		p.MaxLength = 64
+	}
+	if p.MaxRepeat == 0 {
+		p.MaxRepeat = 3
+	}
+	return p
+}
+
+/*
+Validate returns a `*PasswordPolicyError` with all the violations of the password, nil when it's valid. userInputs are
+the user info the password must not contain, e.g. the username and email, compared case-insensitively; the parts of an
+email are checked too, and inputs shorter than 3 characters are ignored.
+The `Breached` source is only queried when the other rules pass. When it fails, its error is set as `BreachedErr` of the
+returned `*PasswordPolicyError`, thus the caller chooses to reject the password or to accept it while the source is down:
+
+	if errors.As(err, &policyErr) && len(policyErr.Violations) == 0 {
+		log.Printf("password not checked against the breaches: %v", policyErr.BreachedErr)
+		err = nil
+	}
+*/
+func (p PasswordPolicy) Validate(password string, userInputs ...string) error {
+	return p.ValidateContext(context.Background(), password, userInputs...)
+}
+
+// ValidateContext is `Validate` with a ctx for the `Breached` lookup, e.g. the request context.
+func (p PasswordPolicy) ValidateContext(ctx context.Context, password string, userInputs ...string) error {
+	p = p.withDefaults()
+	var violations []PasswordViolation
+	add := func(code PasswordViolationCode, limit int) {
+		violations = append(violations, PasswordViolation{Code: code, Limit: limit})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add(PasswordTooShort, p.MinLength)
+	}
+	if length > p.MaxLength {
+		add(PasswordTooLong, p.MaxLength)
+	}
+
+	classes := passwordClassesOf(password)
+	if p.RequireLower && !classes.lower {
+		add(PasswordMissingLower, 0)
+	}
+	if p.RequireUpper && !classes.upper {
+		add(PasswordMissingUpper, 0)
+	}
+	if p.RequireDigit && !classes.digit {
+		add(PasswordMissingDigit, 0)
+	}
+	if p.RequireSymbol && !classes.symbol {
+		add(PasswordMissingSymbol, 0)
+	}
+	if p.MinEntropy > 0 && PasswordEntropy(password) < p.MinEntropy {
+		add(PasswordLowEntropy, int(p.MinEntropy))
+	}
+
+	lower := strings.ToLower(password)
+	if !p.AllowUserInfo && containsUserInfo(lower, userInputs) {
+		add(PasswordUserInfo, 0)
+	}
+	repeat, sequence := longestRuns(lower)
+	if repeat > p.MaxRepeat || isRepeatedPattern(lower) {
+		add(PasswordRepeated, p.MaxRepeat)
+	}
+	if sequence > p.MaxRepeat {
+		add(PasswordSequence, p.MaxRepeat)
+	}
+	if !p.AllowCommon && IsCommonPassword(password) {
+		add(PasswordCommon, 0)
+	}
+	if p.Breached != nil && len(violations) == 0 {
+		breached, err := p.Breached.Breached(ctx, password)
+		if err != nil {
+			return &PasswordPolicyError{BreachedErr: err}
+		}
+		if breached {
+			add(PasswordBreached, max(p.Breached.Threshold, 1))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+type passwordClasses struct {
+	lower, upper, digit, symbol, other bool
+}
+
+func passwordClassesOf(password string) passwordClasses {
+	var c passwordClasses
+	for _, r := range password {
+		switch {
+		case r > unicode.MaxASCII && unicode.IsLetter(r):
+			c.other = true
+		case unicode.IsLower(r):
+			c.lower = true
+		case unicode.IsUpper(r):
+			c.upper = true
+		case unicode.IsDigit(r):
+			c.digit = true
+		case !unicode.IsSpace(r):
+			c.symbol = true
+		}
+	}
+	return c
+}
+
+/*
+PasswordEntropy returns a rough estimate of the password entropy in bits: its length times log2 of the size of the
+character classes it uses (26 lowercase, 26 uppercase, 10 digits, 33 symbols, 100 for the non-ASCII letters).
+It overrates the predictable passwords, e.g. "Password1", which are caught by the other rules of `PasswordPolicy`.
+*/
+func PasswordEntropy(password string) float64 {
+	classes := passwordClassesOf(password)
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{classes.lower, 26}, {classes.upper, 26}, {classes.digit, 10}, {classes.symbol, 33}, {classes.other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(utf8.RuneCountInString(password)) * math.Log2(float64(pool))
+}
+
+func containsUserInfo(lowerPassword string, userInputs []string) bool {
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		candidates := []string{input}
+		if local, domain, ok := strings.Cut(input, "@"); ok {
+			candidates = append(candidates, local, strings.Split(domain, ".")[0])
+		}
+		candidates = append(candidates, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+
+		for _, c := range candidates {
+			if utf8.RuneCountInString(c) >= 3 && strings.Contains(lowerPassword, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// longestRuns returns the longest run of the same character, and of consecutive letters or digits in either direction.
+func longestRuns(password string) (int, int) {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0, 0
+	}
+
+	repeat, sequence := 1, 1
+	currentRepeat, currentSequence, step := 1, 1, rune(0)
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == runes[i-1] {
+			currentRepeat++
+		} else {
+			currentRepeat = 1
+		}
+
+		diff := runes[i] - runes[i-1]
+		sameKind := unicode.IsDigit(runes[i]) == unicode.IsDigit(runes[i-1]) && isSequenceRune(runes[i]) && isSequenceRune(runes[i-1])
+		switch {
+		case sameKind && (diff == 1 || diff == -1) && diff == step:
+			currentSequence++
+		case sameKind && (diff == 1 || diff == -1):
+			currentSequence, step = 2, diff
+		default:
+			currentSequence, step = 1, 0
+		}
+
+		repeat = max(repeat, currentRepeat)
+		sequence = max(sequence, currentSequence)
+	}
+	return repeat, sequence
+}
+
+func isSequenceRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+}
+
+// isRepeatedPattern reports whether the password is a pattern of 2 or more characters repeated, e.g. "abcabcabc".
+func isRepeatedPattern(password string) bool {
+	for size := 2; size <= len(password)/2; size++ {
+		if len(password)%size == 0 && strings.Repeat(password[:size], len(password)/size) == password {
+			return true
+		}
+	}
+	return false
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var commonPasswords = sync.OnceValue(func() map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, line := range strings.Split(commonPasswordsList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			set[line] = struct{}{}
+		}
+	}
+	return set
+})
+
+// IsCommonPassword reports whether the password, case-insensitively, is one of the embedded list of the most common passwords.
+func IsCommonPassword(password string) bool {
+	_, ok := commonPasswords()[strings.ToLower(password)]
+	return ok
+}
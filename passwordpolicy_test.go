@@ -0,0 +1,107 @@
+package gohelpers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		want     []PasswordViolationCode
+	}{
+		{"valid", PasswordPolicy{}, "correct horse battery", nil},
+		{"too short", PasswordPolicy{}, "xk9#q", []PasswordViolationCode{PasswordTooShort}},
+		{"too long", PasswordPolicy{MaxLength: 10}, "correct horse battery", []PasswordViolationCode{PasswordTooLong}},
+		{"length in characters", PasswordPolicy{MinLength: 6}, "كلمةسر", nil},
+		{"classes", PasswordPolicy{RequireLower: true, RequireUpper: true, RequireDigit: true, RequireSymbol: true}, "correcthorse",
+			[]PasswordViolationCode{PasswordMissingUpper, PasswordMissingDigit, PasswordMissingSymbol}},
+		{"all classes", PasswordPolicy{RequireLower: true, RequireUpper: true, RequireDigit: true, RequireSymbol: true}, "Correct-Horse7", nil},
+		{"low entropy", PasswordPolicy{MinEntropy: 60}, "horsebattery", []PasswordViolationCode{PasswordLowEntropy}},
+		{"repeated characters", PasswordPolicy{}, "horseeeebattery", []PasswordViolationCode{PasswordRepeated}},
+		{"repeated pattern", PasswordPolicy{}, "horse7horse7", []PasswordViolationCode{PasswordRepeated}},
+		{"sequence", PasswordPolicy{}, "horse1234battery", []PasswordViolationCode{PasswordSequence}},
+		{"reverse sequence", PasswordPolicy{}, "horseDCBAbattery", []PasswordViolationCode{PasswordSequence}},
+		{"longer runs allowed", PasswordPolicy{MaxRepeat: 4}, "horse1234battery", nil},
+		{"common", PasswordPolicy{}, "Password1", []PasswordViolationCode{PasswordCommon}},
+		{"common allowed", PasswordPolicy{AllowCommon: true}, "Password1", nil},
+		{"several", PasswordPolicy{}, "1234", []PasswordViolationCode{PasswordTooShort, PasswordSequence}},
+	}
+
+	for _, c := range cases {
+		err := c.policy.Validate(c.password)
+		var got []PasswordViolationCode
+		var policyErr *PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			for _, v := range policyErr.Violations {
+				got = append(got, v.Code)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: unexpected error %v", c.name, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestPasswordPolicy_UserInfo(t *testing.T) {
+	policy := PasswordPolicy{}
+	for _, password := range []string{"my-Mbougarne-pass", "john.doe.rocks", "EXAMPLE rocks!"} {
+		err := policy.Validate(password, "mbougarne", "john.doe@example.com")
+		var policyErr *PasswordPolicyError
+		if !errors.As(err, &policyErr) || !policyErr.Has(PasswordUserInfo) {
+			t.Errorf("%q: expected PasswordUserInfo, got %v", password, err)
+		}
+	}
+
+	if err := policy.Validate("correct horse battery", "mbougarne", "jo@example.com"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := (PasswordPolicy{AllowUserInfo: true}).Validate("mbougarne rocks", "mbougarne"); err != nil {
+		t.Errorf("AllowUserInfo: unexpected error %v", err)
+	}
+}
+
+func TestPasswordViolation_Message(t *testing.T) {
+	err := PasswordPolicy{MinLength: 10}.Validate("abcd")
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected a *PasswordPolicyError, got %v", err)
+	}
+
+	if got := policyErr.Violations[0].Message("en"); got != "the password must be at least 10 characters long" {
+		t.Errorf("en: %q", got)
+	}
+	if got := policyErr.Violations[0].Message("ar"); got != "يجب أن تتكون كلمة المرور من 10 أحرف على الأقل" {
+		t.Errorf("ar: %q", got)
+	}
+	if got := policyErr.Violations[0].Message("fr"); got != policyErr.Violations[0].Message("en") {
+		t.Errorf("an unknown language should fall back to English, got %q", got)
+	}
+	if err.Error() != "the password must be at least 10 characters long, the password must not contain sequences like abcd or 1234" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	// every code has both languages
+	for _, lang := range []string{"en", "ar"} {
+		if len(PasswordPolicyMessages[lang]) != 12 {
+			t.Errorf("%s: %d messages", lang, len(PasswordPolicyMessages[lang]))
+		}
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	if got := PasswordEntropy(""); got != 0 {
+		t.Errorf("empty: %v", got)
+	}
+	if got := PasswordEntropy("abcdefgh"); got < 37.6 || got > 37.7 { // 8 * log2(26)
+		t.Errorf("lowercase: %v", got)
+	}
+	if PasswordEntropy("Ab1!Ab1!") <= PasswordEntropy("abababab") {
+		t.Error("more classes should give more entropy")
+	}
+}
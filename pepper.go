@@ -0,0 +1,165 @@
+package gohelpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const pepperPrefix = "$pepper$v="
+
+/*
+PepperRing holds the pepper keys by version. A pepper is a server-side secret mixed into the password hashes and kept out
+of the database, thus a leaked users table can't be cracked without it. Keep the old versions in Keys after a rotation,
+the hashes of these versions are verified, then rehashed with the current one by `VerifyAndRehash`.
+*/
+type PepperRing struct {
+	Current string            // version of the new hashes
+	Keys    map[string][]byte // pepper keys by version
+}
+
+/*
+PepperRingFromEnv reads a `PepperRing` from the env var, formatted as comma-separated `version:base64key` entries, the
+first one is the current version:
+
+	PASSWORD_PEPPER=2:c2Vjb25kIHBlcHBlcg==,1:Zmlyc3QgcGVwcGVy
+
+A value without a version is the base64 key of version "1". An empty var returns nil and no error: no pepper.
+*/
+func PepperRingFromEnv(name string) (*PepperRing, error) {
+	value := strings.TrimSpace(GetEnvKey(name))
+	if value == "" {
+		return nil, nil
+	}
+	if !strings.Contains(value, ":") {
+		value = "1:" + value
+	}
+
+	ring := &PepperRing{Keys: map[string][]byte{}}
+	for _, entry := range strings.Split(value, ",") {
+		version, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || version == "" || strings.Contains(version, "$") {
+			return nil, fmt.Errorf("%s: invalid pepper entry %q", name, entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) == 0 {
+			return nil, fmt.Errorf("%s: invalid pepper key of version %q", name, version)
+		}
+		if ring.Current == "" {
+			ring.Current = version
+		}
+		ring.Keys[version] = key
+	}
+	return ring, nil
+}
+
+/*
+PepperedHasher is a `PasswordHasher` that peppers the passwords before hashing them with Hasher: the password is replaced
+by its base64 HMAC-SHA256 under the current pepper, and the version is stored in front of the inner hash:
+
+	$pepper$v=2$2a$10$...
+
+Set it as the `DefaultPasswordHasher` to pepper the new hashes, the existing unpeppered ones still verify and are
+rehashed with the pepper by `VerifyAndRehash`:
+
+	ring, err := gohelpers.PepperRingFromEnv("PASSWORD_PEPPER")
+	gohelpers.DefaultPasswordHasher = gohelpers.PepperedHasher{Hasher: gohelpers.BcryptHasher{}, Ring: ring}
+
+The peppered hashes can't be verified without the ring.
+*/
+type PepperedHasher struct {
+	Hasher PasswordHasher // inner hasher, default: BcryptHasher
+	Ring   *PepperRing    // required
+}
+
+func (h PepperedHasher) Hash(password string) (string, error) {
+	if h.Ring == nil {
+		return "", fmt.Errorf("%w: missing pepper ring", ErrPasswordHasherMisconfigured)
+	}
+	key, ok := h.Ring.Keys[h.Ring.Current]
+	if !ok || strings.Contains(h.Ring.Current, "$") {
+		return "", fmt.Errorf("%w: invalid current pepper version %q", ErrPasswordHasherMisconfigured, h.Ring.Current)
+	}
+
+	inner, err := h.hasher().Hash(pepper(key, password))
+	if err != nil {
+		return "", err
+	}
+	return pepperPrefix + h.Ring.Current + inner, nil
+}
+
+func (h PepperedHasher) Verify(password, hashed string) (bool, error) {
+	version, inner, err := parsePeppered(hashed)
+	if err != nil {
+		return false, err
+	}
+	if h.Ring == nil {
+		return false, fmt.Errorf("%w: missing pepper ring", ErrPasswordHasherMisconfigured)
+	}
+	key, ok := h.Ring.Keys[version]
+	if !ok {
+		return false, fmt.Errorf("%w: unknown pepper version %q", ErrPasswordHasherMisconfigured, version)
+	}
+
+	hasher := h.innerHasherOf(inner)
+	if hasher == nil {
+		return false, ErrPasswordHashUnsupported
+	}
+	return hasher.Verify(pepper(key, password), inner)
+}
+
+func (h PepperedHasher) Supports(hashed string) bool {
+	return strings.HasPrefix(hashed, pepperPrefix)
+}
+
+// NeedsRehash reports whether the hash is of an old pepper version, or its inner hash needs a rehash.
+func (h PepperedHasher) NeedsRehash(hashed string) bool {
+	version, inner, err := parsePeppered(hashed)
+	if err != nil || h.Ring == nil || version != h.Ring.Current {
+		return true
+	}
+	hasher := h.hasher()
+	return !hasher.Supports(inner) || hasher.NeedsRehash(inner)
+}
+
+func (h PepperedHasher) hasher() PasswordHasher {
+	if h.Hasher == nil {
+		return BcryptHasher{}
+	}
+	return h.Hasher
+}
+
+// innerHasherOf returns the hasher of the inner hash: the hash could be of a previous inner hasher.
+func (h PepperedHasher) innerHasherOf(inner string) PasswordHasher {
+	if hasher := h.hasher(); hasher.Supports(inner) {
+		return hasher
+	}
+	for _, hasher := range passwordHashers {
+		if hasher.Supports(inner) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+func parsePeppered(hashed string) (string, string, error) {
+	rest, ok := strings.CutPrefix(hashed, pepperPrefix)
+	if !ok {
+		return "", "", errors.New("not a peppered hash")
+	}
+	version, inner, ok := strings.Cut(rest, "$")
+	if !ok || version == "" {
+		return "", "", errors.New("malformed peppered hash")
+	}
+	return version, "$" + inner, nil
+}
+
+// pepper returns the base64 HMAC-SHA256 of the password under the key, 44 bytes that fit bcrypt.
+func pepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
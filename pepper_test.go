@@ -0,0 +1,86 @@
+package gohelpers
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPepperRingFromEnv(t *testing.T) {
+	os.Setenv("TEST_PASSWORD_PEPPER", "2:c2Vjb25k, 1:Zmlyc3Q=")
+	defer os.Unsetenv("TEST_PASSWORD_PEPPER")
+
+	ring, err := PepperRingFromEnv("TEST_PASSWORD_PEPPER")
+	if err != nil || ring.Current != "2" || string(ring.Keys["2"]) != "second" || string(ring.Keys["1"]) != "first" {
+		t.Fatalf("PepperRingFromEnv = %+v, %v", ring, err)
+	}
+
+	os.Setenv("TEST_PASSWORD_PEPPER", "Zmlyc3Q=")
+	if ring, err := PepperRingFromEnv("TEST_PASSWORD_PEPPER"); err != nil || ring.Current != "1" || string(ring.Keys["1"]) != "first" {
+		t.Fatalf("pepper without version: %+v, %v", ring, err)
+	}
+
+	for _, invalid := range []string{"2:not base64!", ":Zmlyc3Q=", "a$b:Zmlyc3Q=", "raw-pepper!"} {
+		os.Setenv("TEST_PASSWORD_PEPPER", invalid)
+		if _, err := PepperRingFromEnv("TEST_PASSWORD_PEPPER"); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+
+	if ring, err := PepperRingFromEnv("TEST_PASSWORD_PEPPER_UNSET"); ring != nil || err != nil {
+		t.Fatalf("unset var: %+v, %v", ring, err)
+	}
+}
+
+func TestPepperedHasher(t *testing.T) {
+	old := DefaultPasswordHasher
+	defer func() { DefaultPasswordHasher = old }()
+
+	ring := &PepperRing{Current: "1", Keys: map[string][]byte{"1": []byte("first")}}
+	hasher := PepperedHasher{Hasher: BcryptHasher{Cost: bcrypt.MinCost}, Ring: ring}
+
+	legacy, _ := BcryptHasher{Cost: bcrypt.MinCost}.Hash("secret")
+	hashed, err := hasher.Hash("secret")
+	if err != nil || !strings.HasPrefix(hashed, "$pepper$v=1$2a$04$") {
+		t.Fatalf("Hash = %q, %v", hashed, err)
+	}
+	if ok, err := hasher.Verify("secret", hashed); !ok || err != nil {
+		t.Fatalf("Verify = %v, %v", ok, err)
+	}
+	if ok, _ := hasher.Verify("wrong", hashed); ok {
+		t.Fatal("a wrong password matched")
+	}
+	// useless without the pepper
+	if ok, _ := (PepperedHasher{Ring: &PepperRing{Current: "1", Keys: map[string][]byte{"1": []byte("other")}}}).Verify("secret", hashed); ok {
+		t.Fatal("the hash matched with another pepper")
+	}
+
+	DefaultPasswordHasher = hasher
+	if !VerifyHashedPassword("secret", hashed) || !VerifyHashedPassword("secret", legacy) {
+		t.Fatal("the peppered and legacy hashes should both verify")
+	}
+
+	// legacy hashes are peppered at login
+	ok, newHash, err := VerifyAndRehash("secret", legacy)
+	if !ok || err != nil || !strings.HasPrefix(newHash, "$pepper$v=1$") {
+		t.Fatalf("legacy: %v, %q, %v", ok, newHash, err)
+	}
+
+	// rotation: the old version still verifies and is rehashed with the current one
+	ring.Keys["2"] = []byte("second")
+	ring.Current = "2"
+	ok, newHash, err = VerifyAndRehash("secret", hashed)
+	if !ok || err != nil || !strings.HasPrefix(newHash, "$pepper$v=2$") {
+		t.Fatalf("rotation: %v, %q, %v", ok, newHash, err)
+	}
+	if ok, newHash, _ := VerifyAndRehash("secret", newHash); !ok || newHash != "" {
+		t.Fatalf("up to date: %v, %q", ok, newHash)
+	}
+
+	delete(ring.Keys, "1")
+	if _, err := hasher.Verify("secret", hashed); err == nil {
+		t.Fatal("expected an error for an unknown pepper version")
+	}
+}
@@ -0,0 +1,56 @@
+package gohelpers
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+ReplayCache remembers single-use ids, e.g. the jti of the DPoP proofs, until they expire. `MemoryReplayCache` fits a single
+instance, implement it over a shared store (e.g. Redis SET NX with a TTL) when the service runs on several instances.
+*/
+type ReplayCache interface {
+	// Seen records the id until expiresAt, and reports whether it was already recorded, thus a replay.
+	Seen(id string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryReplayCache is an in-memory `ReplayCache`, safe for concurrent use. The expired ids are pruned as new ones are recorded.
+type MemoryReplayCache struct {
+	mu        sync.Mutex
+	ids       map[string]time.Time
+	clock     Clock
+	nextPrune int
+}
+
+const minReplayCachePrune = 1024
+
+// NewMemoryReplayCache returns an empty cache, the clock tells when the ids expire (default: SystemClock).
+func NewMemoryReplayCache(clock ...Clock) *MemoryReplayCache {
+	var c Clock
+	if len(clock) > 0 {
+		c = clock[0]
+	}
+	return &MemoryReplayCache{ids: map[string]time.Time{}, clock: clockOrSystem(c), nextPrune: minReplayCachePrune}
+}
+
+func (c *MemoryReplayCache) Seen(id string, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if until, ok := c.ids[id]; ok && now.Before(until) {
+		return true, nil
+	}
+	c.ids[id] = expiresAt
+
+	if len(c.ids) >= c.nextPrune {
+		for k, until := range c.ids {
+			if !now.Before(until) {
+				delete(c.ids, k)
+			}
+		}
+		c.nextPrune = max(2*len(c.ids), minReplayCachePrune)
+	}
+
+	return false, nil
+}
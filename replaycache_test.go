@@ -0,0 +1,36 @@
+package gohelpers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryReplayCache(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	cache := NewMemoryReplayCache(clock)
+
+	if seen, _ := cache.Seen("a", clock.Now().Add(time.Minute)); seen {
+		t.Fatal("first use reported as a replay")
+	}
+	if seen, _ := cache.Seen("a", clock.Now().Add(time.Minute)); !seen {
+		t.Fatal("second use not reported as a replay")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if seen, _ := cache.Seen("a", clock.Now().Add(time.Minute)); seen {
+		t.Fatal("an expired id should be forgotten")
+	}
+
+	for i := 0; i < minReplayCachePrune; i++ {
+		cache.Seen(fmt.Sprint(i), clock.Now().Add(time.Second))
+	}
+	clock.Advance(time.Minute)
+	cache.Seen("b", clock.Now().Add(time.Minute))
+	for i := 0; i < minReplayCachePrune; i++ {
+		cache.Seen(fmt.Sprint("c", i), clock.Now().Add(time.Minute))
+	}
+	if len(cache.ids) > minReplayCachePrune+2 {
+		t.Fatalf("the expired ids were not pruned, %d ids", len(cache.ids))
+	}
+}
@@ -0,0 +1,158 @@
+package gohelpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultRefreshedTokenHeader is the response header of the re-issued token when `SlidingSession.Header` is not set.
+const DefaultRefreshedTokenHeader = "X-Refreshed-Token"
+
+/*
+SlidingSession re-issues a valid access token that is about to expire, set it in `AuthOptions.Refresh`.
+The fresh token keeps the claims and header (kid...) of the original with a new iat, jti and exp.
+The first login time is kept in the "auth_time" claim, thus the session can't be extended beyond MaxLifetime.
+*/
+type SlidingSession struct {
+	Window      time.Duration // re-issue when the token expires within Window, required
+	TTL         time.Duration // lifetime of the fresh token. Default: the lifetime of the original (exp - iat), or DefaultTokenTTL
+	MaxLifetime time.Duration // absolute session lifetime from the first iat, 0 means no cap
+
+	Header string // response header of the fresh token. Default: DefaultRefreshedTokenHeader
+	// Cookie is optional, the fresh token is also written to its access cookie. Use the options of the login cookies,
+	// thus the fresh cookie replaces the first one (same name, domain and path). With `CookieOptions.CSRFSecret`, the
+	// CSRF cookie is re-issued too, with the same token, thus it doesn't expire before the access cookie.
+	Cookie *CookieOptions
+
+	// Signer of the fresh token. Default: HS256 with `ParseOptions.Secret`, both are required when the tokens are
+	// verified with `ParseOptions.Key` or `ParseOptions.KeyResolver`.
+	Method jwt.SigningMethod
+	Key    interface{}
+}
+
+// validate checks the config against the verifier options, `Authenticate` rejects the requests when it's invalid.
+func (s *SlidingSession) validate(opts ParseOptions) error {
+	switch {
+	case s.Window <= 0:
+		return &TokenError{Kind: TokenMisconfigured, Err: errors.New("the sliding session window is required")}
+	case (s.Method == nil) != (s.Key == nil):
+		return &TokenError{Kind: TokenMisconfigured, Err: errors.New("the sliding session Method and Key must be set together")}
+	case s.Method == nil && (len(opts.Secret) == 0 || opts.KeyResolver != nil):
+		return &TokenError{Kind: TokenMisconfigured, Err: errors.New("the sliding session Method and Key are required when the tokens are not verified with a secret")}
+	}
+	return nil
+}
+
+// refresh re-issues the token when it's within the window, and writes it to the response. The error is for a failed re-issue.
+func (s *SlidingSession) refresh(w http.ResponseWriter, r *http.Request, token *jwt.Token, opts ParseOptions) error {
+	fresh, err := s.reissue(token, opts)
+	if err != nil || fresh == "" {
+		return err
+	}
+
+	header := s.Header
+	if header == "" {
+		header = DefaultRefreshedTokenHeader
+	}
+	w.Header().Set(header, fresh)
+
+	if s.Cookie != nil {
+		cookieOpts := *s.Cookie
+		if cookieOpts.Clock == nil {
+			cookieOpts.Clock = opts.Clock
+		}
+		access := cookieOpts.newCookie(cookieOpts.AccessCookieName(), fresh, true)
+		http.SetCookie(w, access)
+
+		if len(cookieOpts.CSRFSecret) > 0 {
+			// the CSRF token of the request is kept, the client doesn't have to re-read it
+			subject := unverifiedSubject(fresh)
+			csrf, err := r.Cookie(cookieOpts.CSRFCookieName())
+			csrfToken := ""
+			if err == nil && VerifyCSRFToken(cookieOpts.CSRFSecret, csrf.Value, subject) {
+				csrfToken = csrf.Value
+			} else if csrfToken, err = NewCSRFToken(cookieOpts.CSRFSecret, subject); err != nil {
+				return &TokenError{Kind: TokenMisconfigured, Err: fmt.Errorf("sliding session CSRF token: %w", err)}
+			}
+			cookieOpts.setCSRFCookie(w, access, csrfToken)
+		}
+	}
+	return nil
+}
+
+// reissue returns the fresh token, empty when the token is not within the window or the session reached its max lifetime.
+func (s *SlidingSession) reissue(token *jwt.Token, opts ParseOptions) (string, error) {
+	if err := s.validate(opts); err != nil {
+		return "", err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", nil
+	}
+
+	now := clockOrSystem(opts.Clock).Now()
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil || exp.Time.Sub(now) > s.Window {
+		return "", nil
+	}
+	iat, _ := claims.GetIssuedAt()
+
+	ttl := s.TTL
+	if ttl <= 0 && iat != nil {
+		ttl = exp.Time.Sub(iat.Time)
+	}
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	newExp := now.Add(ttl)
+
+	authTime := now
+	if iat != nil {
+		authTime = iat.Time
+	}
+	if v, ok := claims["auth_time"].(float64); ok {
+		authTime = time.Unix(int64(v), 0)
+	}
+	if s.MaxLifetime > 0 {
+		if deadline := authTime.Add(s.MaxLifetime); newExp.After(deadline) {
+			newExp = deadline
+		}
+	}
+	if !newExp.After(exp.Time) {
+		// the session reached its max lifetime, the token expires as planned
+		return "", nil
+	}
+
+	fresh := jwt.MapClaims{}
+	for k, v := range claims {
+		fresh[k] = v
+	}
+	fresh["iat"] = now.Unix()
+	fresh["exp"] = newExp.Unix()
+	fresh["jti"] = newJTI()
+	fresh["auth_time"] = authTime.Unix()
+
+	method, key := s.Method, s.Key
+	if method == nil || key == nil {
+		method, key = jwt.SigningMethodHS256, opts.Secret
+	}
+
+	// the header fields (kid...) pick the verification key, e.g. of a KeyResolver
+	freshToken := jwt.NewWithClaims(method, fresh)
+	for k, v := range token.Header {
+		if k != "alg" {
+			freshToken.Header[k] = v
+		}
+	}
+
+	signed, err := freshToken.SignedString(key)
+	if err != nil {
+		return "", &TokenError{Kind: TokenMisconfigured, Err: fmt.Errorf("sliding session re-issue: %w", err)}
+	}
+
+	return signed, nil
+}
@@ -0,0 +1,134 @@
+package gohelpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSlidingSession(t *testing.T) {
+	secret := []byte("s3cr3t")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	handler := Authenticate(AuthOptions{
+		ParseOptions: ParseOptions{Secret: secret, Clock: clock},
+		Refresh:      &SlidingSession{Window: 2 * time.Minute, MaxLifetime: 25 * time.Minute, Cookie: &CookieOptions{Name: "access_token", Domain: "x.local", Path: "/api", Insecure: true}},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(tok string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	verifier, _ := NewVerifier(ParseOptions{Secret: secret, Clock: clock})
+	tok, _ := NewToken(WithSecret(secret), WithClock(clock), WithTTL(10*time.Minute), WithSubject("42"), WithClaim("role", "admin"))
+
+	// far from expiry: nothing to do
+	if rec := serve(tok); rec.Header().Get(DefaultRefreshedTokenHeader) != "" {
+		t.Fatal("token far from expiry should not be re-issued")
+	}
+
+	// within the window: re-issued with the same claims
+	clock.Advance(9 * time.Minute)
+	rec := serve(tok)
+	fresh := rec.Header().Get(DefaultRefreshedTokenHeader)
+	if fresh == "" || len(rec.Result().Cookies()) != 1 {
+		t.Fatal("token within the window should be re-issued in the header and the cookie")
+	}
+	if c := rec.Result().Cookies()[0]; c.Name != "access_token" || c.Value != fresh || c.Domain != "x.local" || c.Path != "/api" || c.Secure {
+		t.Fatalf("the fresh cookie should keep the cookie options: %+v", c)
+	}
+	claims, err := VerifyClaims[jwt.MapClaims](verifier, fresh)
+	if err != nil {
+		t.Fatalf("fresh token is invalid: %v", err)
+	}
+	exp, _ := claims.GetExpirationTime()
+	if claims["sub"] != "42" || claims["role"] != "admin" || claims["auth_time"] != float64(start.Unix()) {
+		t.Fatalf("unexpected fresh claims: %v", claims)
+	}
+	if want := clock.Now().Add(10 * time.Minute); !exp.Time.Equal(want) {
+		t.Fatalf("fresh exp = %v, want %v", exp.Time, want)
+	}
+
+	// the next refresh is capped by the max lifetime from the first login
+	clock.Advance(9 * time.Minute)
+	fresh = serve(fresh).Header().Get(DefaultRefreshedTokenHeader)
+	claims, _ = VerifyClaims[jwt.MapClaims](verifier, fresh)
+	exp, _ = claims.GetExpirationTime()
+	if want := start.Add(25 * time.Minute); !exp.Time.Equal(want) {
+		t.Fatalf("capped exp = %v, want %v", exp.Time, want)
+	}
+
+	// the session is over: no more refresh
+	clock.Advance(6 * time.Minute)
+	if rec := serve(fresh); rec.Header().Get(DefaultRefreshedTokenHeader) != "" {
+		t.Fatal("token beyond the max lifetime should not be re-issued")
+	}
+}
+
+func TestSlidingSession_Signer(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	parseOpts := ParseOptions{Key: pub, AllowedMethods: []string{"EdDSA"}, Clock: clock}
+	tok, _ := NewToken(WithSigner(jwt.SigningMethodEdDSA, priv), WithClock(clock), WithTTL(10*time.Minute))
+	clock.Advance(9 * time.Minute)
+
+	serve := func(refresh *SlidingSession) *httptest.ResponseRecorder {
+		handler := Authenticate(AuthOptions{ParseOptions: parseOpts, Refresh: refresh})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// the tokens are verified with a key, there's no secret to fall back on
+	for _, refresh := range []*SlidingSession{
+		{Window: 2 * time.Minute},
+		{Window: 2 * time.Minute, Key: priv},
+		{Method: jwt.SigningMethodEdDSA, Key: priv},
+	} {
+		if rec := serve(refresh); rec.Code != http.StatusInternalServerError {
+			t.Errorf("%+v: got %d, want 500", refresh, rec.Code)
+		}
+	}
+
+	rec := serve(&SlidingSession{Window: 2 * time.Minute, Method: jwt.SigningMethodEdDSA, Key: priv})
+	verifier, _ := NewVerifier(parseOpts)
+	if ok, err := verifier.Verify(rec.Header().Get(DefaultRefreshedTokenHeader)); rec.Code != http.StatusOK || !ok {
+		t.Fatalf("got %d, the fresh token should be signed with the key: %v", rec.Code, err)
+	}
+}
+
+func TestSlidingSession_KeyResolver(t *testing.T) {
+	secret := []byte("globex-s3cr3t")
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	parseOpts := ParseOptions{
+		Clock:       clock,
+		KeyResolver: TenantResolver{"globex": {Keys: map[string]interface{}{"hs-1": secret}}},
+	}
+	tok, _ := NewToken(WithSecret(secret), WithClock(clock), WithTTL(10*time.Minute), WithIssuer("globex"), WithHeader("kid", "hs-1"))
+	clock.Advance(9 * time.Minute)
+
+	refresh := &SlidingSession{Window: 2 * time.Minute, Method: jwt.SigningMethodHS256, Key: secret}
+	handler := Authenticate(AuthOptions{ParseOptions: parseOpts, Refresh: refresh})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	fresh := rec.Header().Get(DefaultRefreshedTokenHeader)
+	verifier, _ := NewVerifier(parseOpts)
+	if _, err := verifier.Parse(fresh); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("got %d, the fresh token should keep its kid: %v", rec.Code, err)
+	}
+}
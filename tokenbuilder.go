@@ -0,0 +1,185 @@
+package gohelpers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultTokenTTL is the lifetime of the tokens that don't set their own exp (`GenerateJwtToken` without claims, `NewToken` without `WithTTL`).
+const DefaultTokenTTL = 12 * time.Minute
+
+// TokenOption configures a token built by `NewToken` or a `TokenIssuer`.
+type TokenOption func(*tokenConfig)
+
+type tokenConfig struct {
+	subject   string
+	issuer    string
+	audience  []string
+	ttl       time.Duration
+	notBefore time.Time
+	claims    map[string]interface{}
+	header    map[string]interface{}
+	method    jwt.SigningMethod
+	key       interface{}
+	clock     Clock
+	err       error // the first invalid option
+}
+
+// WithSubject sets the sub claim.
+func WithSubject(subject string) TokenOption {
+	return func(c *tokenConfig) { c.subject = subject }
+}
+
+// WithTTL sets the token lifetime, exp = now + ttl. Default: DefaultTokenTTL. A ttl <= 0 is an error of `NewToken`.
+func WithTTL(ttl time.Duration) TokenOption {
+	return func(c *tokenConfig) {
+		if ttl <= 0 && c.err == nil {
+			c.err = fmt.Errorf("the token ttl must be positive, got %s", ttl)
+		}
+		c.ttl = ttl
+	}
+}
+
+// WithIssuer sets the iss claim.
+func WithIssuer(issuer string) TokenOption {
+	return func(c *tokenConfig) { c.issuer = issuer }
+}
+
+// WithAudience sets the aud claim, a string for one audience or an array for more.
+func WithAudience(audience ...string) TokenOption {
+	return func(c *tokenConfig) { c.audience = audience }
+}
+
+// WithNotBefore sets the nbf claim.
+func WithNotBefore(notBefore time.Time) TokenOption {
+	return func(c *tokenConfig) { c.notBefore = notBefore }
+}
+
+// WithClaim adds a custom claim. The registered claims set by the other options take precedence over it.
+func WithClaim(key string, value interface{}) TokenOption {
+	return func(c *tokenConfig) {
+		if c.claims == nil {
+			c.claims = map[string]interface{}{}
+		}
+		c.claims[key] = value
+	}
+}
+
+// WithHeader adds a header field, e.g. WithHeader("kid", "2024-01"). The "alg" header is always set by the signer.
+func WithHeader(key string, value interface{}) TokenOption {
+	return func(c *tokenConfig) {
+		if c.header == nil {
+			c.header = map[string]interface{}{}
+		}
+		c.header[key] = value
+	}
+}
+
+// WithSigner sets the signing method and key, e.g. WithSigner(jwt.SigningMethodEdDSA, privateKey).
+func WithSigner(method jwt.SigningMethod, key interface{}) TokenOption {
+	return func(c *tokenConfig) {
+		c.method = method
+		c.key = key
+	}
+}
+
+// WithSecret signs the token with HS256 and the secret, a shortcut of WithSigner(jwt.SigningMethodHS256, secret).
+func WithSecret(secret []byte) TokenOption {
+	return WithSigner(jwt.SigningMethodHS256, secret)
+}
+
+// WithClock sets the clock of iat, exp and the TTL. Default: SystemClock.
+func WithClock(clock Clock) TokenOption {
+	return func(c *tokenConfig) { c.clock = clock }
+}
+
+/*
+TokenIssuer holds the default options of a service, so that all its tokens carry consistent iss, aud, ttl and signer:
+
+	issuer, err := gohelpers.NewTokenIssuer(gohelpers.WithIssuer("auth.example.com"), gohelpers.WithAudience("api"), gohelpers.WithSecret(secret))
+	token, err := issuer.NewToken(gohelpers.WithSubject(userID), gohelpers.WithClaim("role", "admin"))
+*/
+type TokenIssuer struct {
+	defaults []TokenOption
+}
+
+// NewTokenIssuer returns a `TokenIssuer` that applies the defaults before the options of every token, the error is for an invalid default, e.g. a ttl <= 0.
+func NewTokenIssuer(defaults ...TokenOption) (*TokenIssuer, error) {
+	cfg := tokenConfig{}
+	for _, opt := range defaults {
+		opt(&cfg)
+	}
+	if cfg.err != nil {
+		return nil, cfg.err
+	}
+	return &TokenIssuer{defaults: defaults}, nil
+}
+
+// NewToken builds and signs a token with the issuer defaults, overridden by opts.
+func (i *TokenIssuer) NewToken(opts ...TokenOption) (string, error) {
+	cfg := tokenConfig{}
+	for _, opt := range i.defaults {
+		opt(&cfg)
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.err != nil {
+		return "", cfg.err
+	}
+	if cfg.method == nil || cfg.key == nil {
+		return "", errors.New("missing signer, use WithSecret or WithSigner")
+	}
+
+	token := jwt.NewWithClaims(cfg.method, cfg.buildClaims(clockOrSystem(cfg.clock).Now().UTC()))
+	for k, v := range cfg.header {
+		if k != "alg" {
+			token.Header[k] = v
+		}
+	}
+
+	return token.SignedString(cfg.key)
+}
+
+// NewToken builds and signs a token from the options, a signer (`WithSecret` or `WithSigner`) is required.
+func NewToken(opts ...TokenOption) (string, error) {
+	return (&TokenIssuer{}).NewToken(opts...)
+}
+
+func (c tokenConfig) buildClaims(now time.Time) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	for k, v := range c.claims {
+		claims[k] = v
+	}
+
+	ttl := c.ttl
+	if ttl == 0 {
+		ttl = DefaultTokenTTL
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	claims["jti"] = newJTI()
+
+	if !c.notBefore.IsZero() {
+		claims["nbf"] = c.notBefore.Unix()
+	}
+	if c.subject != "" {
+		claims["sub"] = c.subject
+	}
+	if c.issuer != "" {
+		claims["iss"] = c.issuer
+	}
+	switch len(c.audience) {
+	case 0:
+	case 1:
+		claims["aud"] = c.audience[0]
+	default:
+		claims["aud"] = c.audience
+	}
+
+	return claims
+}
@@ -0,0 +1,87 @@
+package gohelpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestNewToken(t *testing.T) {
+	secret := []byte("s3cr3t")
+	tok, err := NewToken(
+		WithSecret(secret),
+		WithSubject("42"),
+		WithTTL(time.Hour),
+		WithIssuer("auth.local"),
+		WithAudience("api"),
+		WithClaim("role", "admin"),
+		WithHeader("kid", "k1"),
+	)
+	if err != nil {
+		t.Fatalf("NewToken error: %v", err)
+	}
+
+	verifier, _ := NewVerifier(ParseOptions{Secret: secret, Audience: "api", Issuer: "auth.local"})
+	token, err := verifier.Parse(tok)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	exp, _ := claims.GetExpirationTime()
+	if claims["sub"] != "42" || claims["role"] != "admin" || claims["jti"] == nil || claims["iat"] == nil {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+	if d := time.Until(exp.Time); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("unexpected exp, expires in %v", d)
+	}
+	if token.Header["kid"] != "k1" {
+		t.Fatalf("unexpected header: %v", token.Header)
+	}
+}
+
+func TestNewToken_MissingSigner(t *testing.T) {
+	if _, err := NewToken(WithSubject("42")); err == nil {
+		t.Fatal("expected an error without a signer")
+	}
+}
+
+func TestNewToken_InvalidTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Minute} {
+		if _, err := NewToken(WithSecret([]byte("s3cr3t")), WithTTL(ttl)); err == nil {
+			t.Errorf("%s: expected an error", ttl)
+		}
+		if _, err := NewTokenIssuer(WithSecret([]byte("s3cr3t")), WithTTL(ttl)); err == nil {
+			t.Errorf("%s: expected an error of NewTokenIssuer", ttl)
+		}
+	}
+}
+
+func TestTokenIssuer_Defaults(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	issuer, err := NewTokenIssuer(
+		WithSigner(jwt.SigningMethodEdDSA, priv),
+		WithIssuer("auth.local"),
+		WithAudience("api", "admin"),
+	)
+	if err != nil {
+		t.Fatalf("NewTokenIssuer error: %v", err)
+	}
+
+	tok, err := issuer.NewToken(WithSubject("42"), WithAudience("api"))
+	if err != nil {
+		t.Fatalf("NewToken error: %v", err)
+	}
+
+	verifier, _ := NewVerifier(ParseOptions{Key: pub, AllowedMethods: []string{"EdDSA"}, Issuer: "auth.local", Audience: "api"})
+	token, err := verifier.Parse(tok)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if aud := token.Claims.(jwt.MapClaims)["aud"]; aud != "api" {
+		t.Fatalf("per-token option should override the default aud, got %v", aud)
+	}
+}
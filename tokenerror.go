@@ -0,0 +1,156 @@
+package gohelpers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenErrorKind tells why a token was rejected.
+type TokenErrorKind int
+
+const (
+	TokenInvalid       TokenErrorKind = iota // any other validation failure
+	TokenMissing                             // no token in the request
+	TokenMalformed                           // not a JWT
+	TokenExpired                             // exp is in the past
+	TokenNotYetValid                         // nbf or iat is in the future
+	TokenBadSignature                        // signature doesn't match the key
+	TokenBadMethod                           // signing method is not allowed
+	TokenBadAudience                         // aud doesn't match
+	TokenBadIssuer                           // iss doesn't match
+	TokenRevoked                             // rejected by ParseOptions.Revoked
+	TokenMisconfigured                       // the verifier itself is misconfigured, e.g. missing secret
+	TokenMissingClaim                        // a required claim is absent
+	TokenTooOld                              // iat is older than ParseOptions.MaxAge
+	TokenBadClaims                           // rejected by a ParseOptions.Validators func
+	TokenConflict                            // the request carries different tokens, see ExtractorChain.RejectConflicts
+	TokenBadProof                            // the DPoP proof is missing or invalid, see DPoPVerifier
+	TokenUnknownKey                          // no key matches the token kid, see TenantResolver
+)
+
+var tokenErrorKindNames = map[TokenErrorKind]string{
+	TokenInvalid:       "invalid token",
+	TokenMissing:       "token missing",
+	TokenMalformed:     "token malformed",
+	TokenExpired:       "token expired",
+	TokenNotYetValid:   "token not valid yet",
+	TokenBadSignature:  "token signature invalid",
+	TokenBadMethod:     "unexpected signing method",
+	TokenBadAudience:   "token audience invalid",
+	TokenBadIssuer:     "token issuer invalid",
+	TokenRevoked:       "token revoked",
+	TokenMisconfigured: "token verifier misconfigured",
+	TokenMissingClaim:  "token required claim missing",
+	TokenTooOld:        "token too old",
+	TokenBadClaims:     "token claims rejected",
+	TokenConflict:      "conflicting tokens in request",
+	TokenBadProof:      "token proof of possession invalid",
+	TokenUnknownKey:    "token key unknown",
+}
+
+func (k TokenErrorKind) String() string {
+	if name, ok := tokenErrorKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenErrorKind(%d)", int(k))
+}
+
+// TokenSource tells where the token was read from.
+type TokenSource string
+
+const (
+	SourceHeader    TokenSource = "header"
+	SourceCookie    TokenSource = "cookie"
+	SourceQuery     TokenSource = "query"
+	SourceForm      TokenSource = "form"
+	SourceWebSocket TokenSource = "websocket"
+	SourceAPIKey    TokenSource = "api_key" // not a JWT, see APIKeys
+)
+
+/*
+TokenError is returned by every func that parses or verifies a token. Match the kind with `errors.Is`:
+
+	if errors.Is(err, gohelpers.ErrTokenExpired) { ... }
+
+The upstream `jwt.Err*` errors are wrapped, thus `errors.Is(err, jwt.ErrTokenExpired)` still works.
+*/
+type TokenError struct {
+	Kind   TokenErrorKind
+	Source TokenSource // empty when the token wasn't read from a request
+	Err    error       // the cause, could be nil
+}
+
+// Sentinels to match a `TokenError` kind with `errors.Is`.
+var (
+	ErrTokenInvalid       = &TokenError{Kind: TokenInvalid}
+	ErrTokenMissing       = &TokenError{Kind: TokenMissing}
+	ErrTokenMalformed     = &TokenError{Kind: TokenMalformed}
+	ErrTokenExpired       = &TokenError{Kind: TokenExpired}
+	ErrTokenNotYetValid   = &TokenError{Kind: TokenNotYetValid}
+	ErrTokenBadSignature  = &TokenError{Kind: TokenBadSignature}
+	ErrTokenBadMethod     = &TokenError{Kind: TokenBadMethod}
+	ErrTokenBadAudience   = &TokenError{Kind: TokenBadAudience}
+	ErrTokenBadIssuer     = &TokenError{Kind: TokenBadIssuer}
+	ErrTokenRevoked       = &TokenError{Kind: TokenRevoked}
+	ErrTokenMisconfigured = &TokenError{Kind: TokenMisconfigured}
+	ErrTokenMissingClaim  = &TokenError{Kind: TokenMissingClaim}
+	ErrTokenTooOld        = &TokenError{Kind: TokenTooOld}
+	ErrTokenBadClaims     = &TokenError{Kind: TokenBadClaims}
+	ErrTokenConflict      = &TokenError{Kind: TokenConflict}
+	ErrTokenBadProof      = &TokenError{Kind: TokenBadProof}
+	ErrTokenUnknownKey    = &TokenError{Kind: TokenUnknownKey}
+)
+
+func (e *TokenError) Error() string {
+	msg := e.Kind.String()
+	if e.Source != "" {
+		msg += " (from " + string(e.Source) + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a `TokenError` of the same kind.
+func (e *TokenError) Is(target error) bool {
+	t, ok := target.(*TokenError)
+	return ok && t.Kind == e.Kind
+}
+
+// newTokenError classifies an error of the jwt parser.
+func newTokenError(err error, source TokenSource) *TokenError {
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		if tokenErr.Source != "" {
+			source = tokenErr.Source
+		}
+		return &TokenError{Kind: tokenErr.Kind, Source: source, Err: tokenErr.Err}
+	}
+
+	kind := TokenInvalid
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		kind = TokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		kind = TokenBadSignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		kind = TokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		kind = TokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		kind = TokenBadAudience
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		kind = TokenBadIssuer
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		kind = TokenMissingClaim
+	}
+
+	return &TokenError{Kind: kind, Source: source, Err: err}
+}
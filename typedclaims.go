@@ -0,0 +1,88 @@
+package gohelpers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/*
+ParseClaims verifies the token and parses its claims straight into T with `jwt.ParseWithClaims`, no JSON round trip:
+
+	claims, err := gohelpers.ParseClaims[jwtCustomClaims](token, secretKey)
+
+T is a claims struct whose pointer implements `jwt.Claims` (e.g. it embeds `jwt.RegisteredClaims`), `jwt.MapClaims`,
+or `gohelpers.Claims[P]`. Other types don't compile.
+*/
+func ParseClaims[T any, PT interface {
+	*T
+	jwt.Claims
+}](tokenString string, secretKey []byte) (T, error) {
+	verifier, err := NewVerifier(ParseOptions{Secret: secretKey})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return VerifyClaims[T, PT](verifier, tokenString)
+}
+
+// VerifyClaims is `ParseClaims` with the rules of the verifier.
+func VerifyClaims[T any, PT interface {
+	*T
+	jwt.Claims
+}](v *Verifier, tokenString string) (T, error) {
+	claims := PT(new(T))
+	if _, err := v.parse(tokenString, claims, ""); err != nil {
+		var zero T
+		return zero, err
+	}
+	return *claims, nil
+}
+
+/*
+Claims are the registered claims with a typed payload. The payload fields are flattened next to the registered ones
+in the token, thus a `Claims[User]` reads the same tokens as a struct that embeds `jwt.RegisteredClaims`:
+
+	type User struct {
+		Username string `json:"username"`
+	}
+
+	claims, err := gohelpers.ParseClaims[gohelpers.Claims[User]](token, secretKey)
+	fmt.Println(claims.Subject, claims.Payload.Username)
+*/
+type Claims[P any] struct {
+	jwt.RegisteredClaims
+	Payload P
+}
+
+// MarshalJSON flattens the payload next to the registered claims, the registered claims win on conflicts.
+func (c Claims[P]) MarshalJSON() ([]byte, error) {
+	merged := map[string]json.RawMessage{}
+
+	payload, err := json.Marshal(c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, &merged); err != nil {
+		return nil, fmt.Errorf("claims payload must be a JSON object: %w", err)
+	}
+
+	registered, err := json.Marshal(c.RegisteredClaims)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(registered, &merged); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON reads the registered claims and the payload from the same JSON object.
+func (c *Claims[P]) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &c.RegisteredClaims); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &c.Payload)
+}
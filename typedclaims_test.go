@@ -0,0 +1,63 @@
+package gohelpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type typedPayload struct {
+	Username  string `json:"username"`
+	AccountID int64  `json:"account_id"`
+}
+
+func TestParseClaims(t *testing.T) {
+	secret := []byte("s3cr3t")
+	cc := createCustomClaim(time.Now().Add(time.Minute))
+	tok, _ := GenerateJwtToken(secret, &cc)
+
+	val, err := ParseClaims[jwtCustomClaims](tok, secret)
+	if err != nil || val.Uuid != cc.Uuid || val.Username != "johnDoe" || val.ExpiresAt == nil {
+		t.Fatalf("ParseClaims[jwtCustomClaims] = %+v, %v", val, err)
+	}
+
+	m, err := ParseClaims[jwt.MapClaims](tok, secret)
+	if err != nil || m["username"] != "johnDoe" {
+		t.Fatalf("ParseClaims[jwt.MapClaims] = %v, %v", m, err)
+	}
+
+	if _, err := ParseClaims[jwtCustomClaims](tok, []byte("wrong")); !errors.Is(err, ErrTokenBadSignature) {
+		t.Fatalf("expected ErrTokenBadSignature, got %v", err)
+	}
+}
+
+func TestClaims_TypedPayload(t *testing.T) {
+	secret := []byte("s3cr3t")
+	in := Claims[typedPayload]{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		Payload: typedPayload{Username: "johnDoe", AccountID: 9007199254740993}, // 2^53 + 1
+	}
+	tok, err := GenerateJwtToken(secret, in)
+	if err != nil {
+		t.Fatalf("GenerateJwtToken error: %v", err)
+	}
+
+	out, err := ParseClaims[Claims[typedPayload]](tok, secret)
+	if err != nil {
+		t.Fatalf("ParseClaims error: %v", err)
+	}
+	if out.Subject != "42" || out.Payload.Username != "johnDoe" || out.Payload.AccountID != in.Payload.AccountID {
+		t.Fatalf("unexpected claims: %+v", out)
+	}
+
+	// the payload is flattened, thus readable as plain custom claims
+	flat, err := ParseClaims[jwtCustomClaims](tok, secret)
+	if err != nil || flat.Username != "johnDoe" || flat.Subject != "42" {
+		t.Fatalf("unexpected flattened claims: %+v, %v", flat, err)
+	}
+}
@@ -0,0 +1,181 @@
+package gohelpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultLeeway is the clock skew tolerated on exp, nbf and iat when `ParseOptions.Leeway` is not set.
+const DefaultLeeway = 30 * time.Second
+
+// NoLeeway disables the clock skew tolerance when set as `ParseOptions.Leeway`, the tokens expire at their exact exp.
+const NoLeeway time.Duration = -1
+
+/*
+Verifier verifies tokens with the same rules everywhere: keys, allowed methods, leeway, audiences, issuers, required claims and clock.
+Build it once with `NewVerifier` and share it, it's safe for concurrent use.
+`VerifyJwtToken`, `GetClaims` and `ParseFromRequest` are thin wrappers over it.
+*/
+type Verifier struct {
+	opts    ParseOptions
+	key     interface{}
+	methods []string
+	issuers []string
+	leeway  time.Duration
+	clock   Clock
+	parser  *jwt.Parser
+	parsers sync.Map // parsers of the KeyResolver rules, by leeway and audiences
+
+	extractor TokenExtractor
+}
+
+// NewVerifier builds a `Verifier` from the options, it fails when there's no key (or `KeyResolver`) to verify tokens with.
+func NewVerifier(opts ParseOptions) (*Verifier, error) {
+	var key interface{}
+	switch {
+	case len(opts.Secret) > 0:
+		key = opts.Secret
+	case opts.Key != nil:
+		key = opts.Key
+	case opts.KeyResolver != nil:
+		// the key is resolved per token
+	default:
+		return nil, &TokenError{Kind: TokenMisconfigured, Err: errors.New("missing secret")}
+	}
+
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{jwt.SigningMethodHS256.Alg()}
+	}
+	leeway := opts.Leeway
+	if leeway == 0 {
+		leeway = DefaultLeeway
+	}
+	leeway = max(leeway, 0)
+
+	clock := clockOrSystem(opts.Clock)
+
+	extractor := opts.Extractor
+	if extractor == nil {
+		extractor = defaultExtractor(opts)
+	}
+
+	return &Verifier{
+		opts:    opts,
+		key:     key,
+		methods: methods,
+		issuers: appendNonEmpty(opts.Issuers, opts.Issuer),
+		leeway:  leeway,
+		clock:   clock,
+		parser:  newParser(opts, clock, leeway, appendNonEmpty(opts.Audiences, opts.Audience)),
+
+		extractor: extractor,
+	}, nil
+}
+
+// Parse verifies the token string and returns the parsed token, its claims are `jwt.MapClaims`.
+func (v *Verifier) Parse(tokenString string) (*jwt.Token, error) {
+	return v.parse(tokenString, jwt.MapClaims{}, "")
+}
+
+// Verify returns (true,nil) when the token is valid.
+func (v *Verifier) Verify(tokenString string) (bool, error) {
+	if _, err := v.Parse(tokenString); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Claims returns the claims of a valid token.
+func (v *Verifier) Claims(tokenString string) (interface{}, error) {
+	token, err := v.Parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return token.Claims, nil
+}
+
+// ClaimsAs verifies the token and unmarshals its claims into dst (pointer to struct).
+func (v *Verifier) ClaimsAs(tokenString string, dst interface{}) error {
+	claims, err := v.Claims(tokenString)
+	if err != nil {
+		return err
+	}
+	return CastJwtClaimsToCustomClaims(claims, dst)
+}
+
+// FromRequest extracts the token from the request (see `ParseOptions`) and verifies it.
+func (v *Verifier) FromRequest(r *http.Request) (*jwt.Token, error) {
+	token, _, err := v.fromRequest(r)
+	return token, err
+}
+
+func (v *Verifier) fromRequest(r *http.Request) (*jwt.Token, TokenSource, error) {
+	raw, source, err := extractTokenString(r, v.extractor)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := v.parse(raw, jwt.MapClaims{}, source)
+	if err == nil && v.opts.DPoP != nil {
+		if err = v.opts.DPoP.checkRequest(r, raw, token); err != nil {
+			return nil, source, newTokenError(err, source)
+		}
+	}
+	return token, source, err
+}
+
+func (v *Verifier) parse(tokenString string, claims jwt.Claims, source TokenSource) (*jwt.Token, error) {
+	parser, keyFunc, leeway := v.parser, jwt.Keyfunc(v.keyFunc), v.leeway
+	if v.opts.KeyResolver != nil {
+		var err error
+		if parser, keyFunc, leeway, err = v.resolve(tokenString); err != nil {
+			return nil, newTokenError(err, source)
+		}
+	}
+
+	token, err := parser.ParseWithClaims(tokenString, claims, keyFunc)
+	if err != nil {
+		return nil, newTokenError(err, source)
+	}
+	if !token.Valid {
+		return nil, &TokenError{Kind: TokenInvalid, Source: source}
+	}
+	if err := v.checkPolicy(token, leeway); err != nil {
+		return nil, newTokenError(err, source)
+	}
+	if v.opts.Revoked != nil && v.opts.Revoked(token) {
+		return nil, &TokenError{Kind: TokenRevoked, Source: source}
+	}
+
+	return token, nil
+}
+
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	if err := checkMethod(token, v.methods); err != nil {
+		return nil, err
+	}
+	return v.key, nil
+}
+
+func checkMethod(token *jwt.Token, methods []string) error {
+	if token.Method == nil || !InSlice(token.Method.Alg(), methods) {
+		return &TokenError{Kind: TokenBadMethod, Err: fmt.Errorf("signing method %v is not allowed", token.Header["alg"])}
+	}
+	return nil
+}
+
+func newParser(opts ParseOptions, clock Clock, leeway time.Duration, audiences []string) *jwt.Parser {
+	parseOpts := []jwt.ParserOption{jwt.WithLeeway(leeway), jwt.WithTimeFunc(clock.Now)}
+	if len(audiences) > 0 {
+		parseOpts = append(parseOpts, jwt.WithAudience(audiences...))
+	}
+	if opts.RequireExp {
+		parseOpts = append(parseOpts, jwt.WithExpirationRequired())
+	}
+	return jwt.NewParser(parseOpts...)
+}
@@ -0,0 +1,116 @@
+package gohelpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenError_Kinds(t *testing.T) {
+	secret := []byte("right")
+	valid := createCustomClaim(time.Now().Add(time.Minute))
+	tokValid, _ := GenerateJwtToken(secret, &valid)
+	expired := createCustomClaim(time.Now().Add(-time.Hour))
+	tokExpired, _ := GenerateJwtToken(secret, &expired)
+	notBefore := jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()}
+	tokNotBefore, _ := GenerateJwtToken(secret, notBefore)
+	tokHS512, _ := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{}).SignedString(secret)
+
+	cases := []struct {
+		name  string
+		token string
+		key   []byte
+		want  *TokenError
+	}{
+		{"malformed", "abc", secret, ErrTokenMalformed},
+		{"expired", tokExpired, secret, ErrTokenExpired},
+		{"not valid yet", tokNotBefore, secret, ErrTokenNotYetValid},
+		{"bad signature", tokValid, []byte("wrong"), ErrTokenBadSignature},
+		{"bad method", tokHS512, secret, ErrTokenBadMethod},
+		{"missing secret", tokValid, nil, ErrTokenMisconfigured},
+	}
+
+	for _, c := range cases {
+		_, err := VerifyJwtToken(c.token, c.key)
+
+		var tokenErr *TokenError
+		if !errors.Is(err, c.want) || !errors.As(err, &tokenErr) {
+			t.Errorf("%s: got %v, want kind %v", c.name, err, c.want.Kind)
+		}
+	}
+
+	// the upstream errors are still matched
+	if _, err := VerifyJwtToken(tokExpired, secret); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("expected jwt.ErrTokenExpired to be wrapped, got %v", err)
+	}
+}
+
+func TestTokenError_Source(t *testing.T) {
+	secret := []byte("s3cr3t")
+	expired := createCustomClaim(time.Now().Add(-time.Hour))
+	tok, _ := GenerateJwtToken(secret, &expired)
+
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: tok})
+	_, err := ParseFromRequest(req, ParseOptions{Secret: secret, CookieName: "access_token"})
+
+	var tokenErr *TokenError
+	if !errors.As(err, &tokenErr) || tokenErr.Kind != TokenExpired || tokenErr.Source != SourceCookie {
+		t.Fatalf("expected an expired token error from cookie, got %v", err)
+	}
+
+	_, err = ParseFromRequest(httptest.NewRequest(http.MethodGet, "http://x.local/api", nil), ParseOptions{Secret: secret})
+	if !errors.Is(err, ErrTokenMissing) {
+		t.Fatalf("expected ErrTokenMissing, got %v", err)
+	}
+}
+
+func TestVerifier_ConsistentRules(t *testing.T) {
+	secret := []byte("s3cr3t")
+	tok, _ := GenerateJwtToken(secret, jwt.MapClaims{
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"aud": "billing",
+		"iss": "auth.local",
+	})
+
+	verifier, err := NewVerifier(ParseOptions{Secret: secret, Audience: "billing", Issuer: "auth.local"})
+	if err != nil {
+		t.Fatalf("NewVerifier error: %v", err)
+	}
+	if ok, err := verifier.Verify(tok); !ok || err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	var claims jwtCustomClaims
+	if err := verifier.ClaimsAs(tok, &claims); err != nil || claims.Issuer != "auth.local" {
+		t.Fatalf("ClaimsAs failed: %+v, %v", claims, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://x.local/api", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	if _, err := verifier.FromRequest(req); err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+
+	other, _ := NewVerifier(ParseOptions{Secret: secret, Audience: "shipping"})
+	if _, err := other.Verify(tok); !errors.Is(err, ErrTokenBadAudience) {
+		t.Fatalf("expected ErrTokenBadAudience, got %v", err)
+	}
+}
+
+func TestVerifier_Revoked(t *testing.T) {
+	secret := []byte("s3cr3t")
+	tok, _ := GenerateJwtToken(secret)
+
+	verifier, _ := NewVerifier(ParseOptions{
+		Secret:  secret,
+		Revoked: func(token *jwt.Token) bool { return true },
+	})
+	if _, err := verifier.Verify(tok); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked, got %v", err)
+	}
+}